@@ -3,280 +3,859 @@ package watchcat
 import (
 	"context"
 	"fmt"
+	"path"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
+	gh "github.com/google/go-github/github"
 	"github.com/kudohamu/watchcat/internal/github"
 	"github.com/kudohamu/watchcat/internal/lmdb"
 	version "github.com/mcuadros/go-version"
 )
 
-// ReleaseChecker represents checker for latest release.
+// ReleaseChecker represents checker for new releases.
 type ReleaseChecker struct {
 	repo      *RepoConfig
 	notifiers notifiers
+	store     lmdb.Store
+	client    *github.Client
 }
 
-// CommitChecker represents checker for latest commit.
+// CommitChecker represents checker for new commits.
 type CommitChecker struct {
 	repo      *RepoConfig
 	notifiers notifiers
+	store     lmdb.Store
+	client    *github.Client
 }
 
-// IssueChecker represents checker for latest issue.
+// IssueChecker represents checker for new issues.
 type IssueChecker struct {
 	repo      *RepoConfig
 	notifiers notifiers
+	store     lmdb.Store
+	client    *github.Client
 }
 
-// PRChecker repositories checker for latest pr.
+// PRChecker repositories checker for new prs.
 type PRChecker struct {
 	repo      *RepoConfig
 	notifiers notifiers
+	store     lmdb.Store
+	client    *github.Client
 }
 
-// TagChecker repositories checker for latest tag.
+// TagChecker repositories checker for new tags.
 type TagChecker struct {
 	repo      *RepoConfig
 	notifiers notifiers
+	store     lmdb.Store
+	client    *github.Client
 }
 
-// Run checks latest release.
+// PRStateChecker represents checker for state changes of pull requests.
+// it checks all of pr_merged, pr_closed, pr_reopened and pr_ready of the repository at once.
+type PRStateChecker struct {
+	repo *RepoConfig
+	// notifiers are keyed by target.
+	notifiers map[string]notifiers
+	store     lmdb.Store
+	client    *github.Client
+}
+
+// maxUpdatedPRs caps pull requests whose states are checked at once.
+const maxUpdatedPRs = 300
+
+// maxBacklog caps new items listed at once to page back to the cursor.
+// items more than the limit are notified at the following checks.
+const maxBacklog = 300
+
+// maxScannedTags caps tags scanned at once to find new ones.
+const maxScannedTags = 1000
+
+// targetPRState is the target of the cursor of PRStateChecker.
+const targetPRState = "pr_state"
+
+// Run checks new releases.
 func (rc *ReleaseChecker) Run() error {
 	repo := &lmdb.Repo{
+		Host:   storeHost(rc.client),
 		Owner:  rc.repo.Owner,
 		Name:   rc.repo.Name,
 		Target: TargetRelease,
 	}
-	if err := repo.Read(); err != nil {
+	if err := repo.Read(rc.store); err != nil {
 		rc.notifiers.Error(err)
 		return err
 	}
-
-	release, err := github.LatestRelease(context.Background(), repo.Owner, repo.Name)
+	filter, err := newVersionFilter(rc.repo)
 	if err != nil {
-		if err != github.ErrNotFound {
-			rc.notifiers.Error(err)
+		rc.notifiers.Error(err)
+		return err
+	}
+
+	// releases are listed in created order, so the highest one is picked from limit releases at first check,
+	// and new releases are looked back up to the current one at the following checks.
+	limit := rc.repo.Limit
+	if repo.Current != "" {
+		limit = fetchLimit(rc.repo, repo)
+	}
+	releases, done, err := rc.client.ReleasesUntil(context.Background(), repo.Owner, repo.Name, limit, func(release *gh.RepositoryRelease) bool {
+		if !filter.match(release.GetTagName(), release.GetPrerelease(), release.GetDraft()) {
+			return false
 		}
+		return repo.Current == "" || version.CompareSimple(repo.Current, release.GetTagName()) < 0
+	}, func(release *gh.RepositoryRelease) bool {
+		return repo.Current != "" && repo.Current == release.GetTagName()
+	})
+	// nothing has changed since the last check.
+	if err == github.ErrNotModified {
+		return nil
+	}
+	if err != nil {
+		reportError(rc.notifiers, err)
 		return err
 	}
 
 	// has new release?
-	if repo.Current != "" && version.CompareSimple(repo.Current, release.GetTagName()) >= 0 {
+	if len(releases) == 0 {
+		done()
 		return nil
 	}
+	reportOverflow(rc.notifiers, rc.repo, repo, len(releases))
+
+	sort.SliceStable(releases, func(i, j int) bool {
+		return version.CompareSimple(releases[i].GetTagName(), releases[j].GetTagName()) < 0
+	})
+	// only the highest release is notified at first check.
+	// otherwise the lowest limit releases are notified, and higher ones are left to the following checks.
+	higher := 0
+	if repo.Current == "" {
+		releases = releases[len(releases)-1:]
+	} else if len(releases) > rc.repo.Limit {
+		higher = len(releases) - rc.repo.Limit
+		releases = releases[:rc.repo.Limit]
+	}
 
 	prev := repo.Current
-	repo.Current = release.GetTagName()
-	if err := repo.Write(); err != nil {
+	repo.Current = releases[len(releases)-1].GetTagName()
+	if err := repo.Write(rc.store); err != nil {
 		rc.notifiers.Error(err)
 		return err
 	}
+	if higher == 0 {
+		done()
+	}
 
-	ni := &NotificationInfo{
-		Owner:     repo.Owner,
-		AvatarURL: rc.repo.avatarURL,
-		RepoName:  repo.Name,
-		Current:   repo.Current,
-		Prev:      prev,
-		Link:      release.GetHTMLURL(),
-		Title:     release.GetTagName(),
-		Body:      release.GetBody(),
-		Target:    repo.Target,
+	// notify from the lowest one.
+	for _, release := range releases {
+		if !filter.bumped(prev, release.GetTagName()) {
+			prev = release.GetTagName()
+			continue
+		}
+		ni := &NotificationInfo{
+			Owner:     repo.Owner,
+			AvatarURL: rc.repo.avatarURL,
+			RepoName:  repo.Name,
+			RepoURL:   rc.client.RepoURL(repo.Owner, repo.Name),
+			Current:   release.GetTagName(),
+			Prev:      prev,
+			Link:      release.GetHTMLURL(),
+			Title:     release.GetTagName(),
+			Body:      release.GetBody(),
+			Target:    repo.Target,
+			CreatedAt: release.GetPublishedAt().Time,
+			Extra: map[string]string{
+				"name":       release.GetName(),
+				"author":     release.GetAuthor().GetLogin(),
+				"prerelease": strconv.FormatBool(release.GetPrerelease()),
+			},
+		}
+		rc.notifiers.Notify(ni)
+		prev = ni.Current
 	}
-	rc.notifiers.Notify(ni)
 	return nil
 }
 
-// Run checks latest commit.
+// Run checks new commits of each watched branch.
 func (c *CommitChecker) Run() error {
+	branches, err := c.branches()
+	if err != nil {
+		reportError(c.notifiers, err)
+		return err
+	}
+
+	var lastErr error
+	for _, branch := range branches {
+		if err := c.runBranch(branch); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// branches returns names of branches to watch, expanding glob patterns.
+// empty name is the default branch.
+func (c *CommitChecker) branches() ([]string, error) {
+	if len(c.repo.Branches) == 0 {
+		return []string{""}, nil
+	}
+
+	var names []string
+	added := map[string]bool{}
+	var all []string
+	fetched := false
+	for _, pattern := range c.repo.Branches {
+		if !strings.ContainsAny(pattern, "*?[") {
+			if !added[pattern] {
+				names = append(names, pattern)
+				added[pattern] = true
+			}
+			continue
+		}
+
+		if !fetched {
+			var err error
+			all, err = c.client.Branches(context.Background(), c.repo.Owner, c.repo.Name)
+			if err != nil {
+				return nil, err
+			}
+			fetched = true
+		}
+		for _, b := range all {
+			if ok, _ := path.Match(pattern, b); ok && !added[b] {
+				names = append(names, b)
+				added[b] = true
+			}
+		}
+	}
+	return names, nil
+}
+
+// runBranch checks new commits of branch.
+func (c *CommitChecker) runBranch(branch string) error {
 	repo := &lmdb.Repo{
+		Host:   storeHost(c.client),
 		Owner:  c.repo.Owner,
 		Name:   c.repo.Name,
 		Target: TargetCommit,
+		Ref:    branch,
 	}
-	if err := repo.Read(); err != nil {
+	if err := repo.Read(c.store); err != nil {
 		c.notifiers.Error(err)
 		return err
 	}
 
-	commit, err := github.LatestCommit(context.Background(), repo.Owner, repo.Name)
+	commits, done, err := c.client.CommitsUntil(context.Background(), repo.Owner, repo.Name, branch, fetchLimit(c.repo, repo), func(commit *gh.RepositoryCommit) bool {
+		return repo.Current == commit.GetSHA()
+	})
+	// nothing has changed since the last check.
+	if err == github.ErrNotModified {
+		return nil
+	}
 	if err != nil {
-		if err != github.ErrNotFound {
-			c.notifiers.Error(err)
-		}
+		reportError(c.notifiers, err)
 		return err
 	}
 
 	// has new commit?
-	if repo.Current == commit.GetSHA() {
+	if len(commits) == 0 {
+		done()
 		return nil
 	}
 
+	reportOverflow(c.notifiers, c.repo, repo, len(commits))
+
+	// pick the oldest limit commits changing watched paths before moving the cursor.
+	// newer commits, and commits from the one failed to be checked, are left to the following checks.
+	var matched []*gh.RepositoryCommit
+	var checkErr error
+	next := len(commits)
+	for next > 0 && len(matched) < c.repo.Limit {
+		commit := commits[next-1]
+		if len(c.repo.Paths) > 0 {
+			files, err := c.client.CommitFiles(context.Background(), repo.Owner, repo.Name, commit.GetSHA())
+			if err != nil {
+				reportError(c.notifiers, err)
+				checkErr = err
+				break
+			}
+			if !matchPaths(c.repo.Paths, files) {
+				next--
+				continue
+			}
+		}
+		matched = append(matched, commit)
+		next--
+	}
+	if next == len(commits) {
+		return checkErr
+	}
+
 	prev := repo.Current
-	repo.Current = commit.GetSHA()
-	if err := repo.Write(); err != nil {
+	repo.Current = commits[next].GetSHA()
+	if err := repo.Write(c.store); err != nil {
 		c.notifiers.Error(err)
 		return err
 	}
+	if next == 0 {
+		done()
+	}
 
-	ni := &NotificationInfo{
-		Owner:     repo.Owner,
-		AvatarURL: c.repo.avatarURL,
-		RepoName:  repo.Name,
-		Current:   repo.Current,
-		Prev:      prev,
-		Link:      commit.GetHTMLURL(),
-		Title:     commit.GetSHA(),
-		Body:      commit.Commit.GetMessage(),
-		Target:    repo.Target,
+	// notify from the oldest one.
+	for _, commit := range matched {
+		ni := &NotificationInfo{
+			Owner:     repo.Owner,
+			AvatarURL: c.repo.avatarURL,
+			RepoName:  repo.Name,
+			RepoURL:   c.client.RepoURL(repo.Owner, repo.Name),
+			Current:   commit.GetSHA(),
+			Prev:      prev,
+			Link:      commit.GetHTMLURL(),
+			Title:     commit.GetSHA(),
+			Body:      commit.Commit.GetMessage(),
+			Target:    repo.Target,
+			CreatedAt: commit.Commit.GetAuthor().GetDate(),
+			Extra: map[string]string{
+				"subject":     strings.SplitN(commit.Commit.GetMessage(), "\n", 2)[0],
+				"author":      commit.GetAuthor().GetLogin(),
+				"author_name": commit.Commit.GetAuthor().GetName(),
+				"branch":      branch,
+			},
+		}
+		c.notifiers.Notify(ni)
+		prev = ni.Current
 	}
-	c.notifiers.Notify(ni)
 
-	return nil
+	return checkErr
 }
 
-// Run checks latest issue.
+// Run checks new issues.
 func (c *IssueChecker) Run() error {
 	repo := &lmdb.Repo{
+		Host:   storeHost(c.client),
 		Owner:  c.repo.Owner,
 		Name:   c.repo.Name,
 		Target: TargetIssue,
 	}
-	if err := repo.Read(); err != nil {
+	if err := repo.Read(c.store); err != nil {
 		c.notifiers.Error(err)
 		return err
 	}
 
-	issue, err := github.LatestIssue(context.Background(), repo.Owner, repo.Name)
+	match, query, err := issueMatcher(c.repo, TargetIssue)
 	if err != nil {
-		if err != github.ErrNotFound {
-			c.notifiers.Error(err)
-		}
+		c.notifiers.Error(err)
+		return err
+	}
+
+	current, parseErr := strconv.ParseInt(repo.Current, 10, 64)
+	issues, done, err := c.client.IssuesUntil(context.Background(), repo.Owner, repo.Name, query, fetchLimit(c.repo, repo), func(issue *gh.Issue) bool {
+		return parseErr == nil && current >= issue.GetID()
+	})
+	// nothing has changed since the last check.
+	if err == github.ErrNotModified {
+		return nil
+	}
+	if err != nil {
+		reportError(c.notifiers, err)
 		return err
 	}
 
 	// has new issue?
-	current, err := strconv.ParseInt(repo.Current, 10, 64)
-	if err == nil && current >= issue.GetID() {
+	if len(issues) == 0 {
+		done()
 		return nil
 	}
+	reportOverflow(c.notifiers, c.repo, repo, len(issues))
+
+	// pick the oldest limit issues matching the filter before moving the cursor.
+	// the cursor moves past unmatched issues too, and newer ones are left to the following checks.
+	var matched []*gh.Issue
+	next := len(issues)
+	for next > 0 && len(matched) < c.repo.Limit {
+		next--
+		if match(issues[next]) {
+			matched = append(matched, issues[next])
+		}
+	}
 
 	prev := repo.Current
-	repo.Current = strconv.FormatInt(issue.GetID(), 10)
-	if err := repo.Write(); err != nil {
+	repo.Current = strconv.FormatInt(issues[next].GetID(), 10)
+	if err := repo.Write(c.store); err != nil {
 		c.notifiers.Error(err)
 		return err
 	}
+	if next == 0 {
+		done()
+	}
 
-	ni := &NotificationInfo{
-		Owner:     repo.Owner,
-		AvatarURL: c.repo.avatarURL,
-		RepoName:  repo.Name,
-		Current:   repo.Current,
-		Prev:      prev,
-		Link:      issue.GetHTMLURL(),
-		Title:     issue.GetTitle(),
-		Body:      issue.GetBody(),
-		Target:    repo.Target,
+	// notify from the oldest one.
+	for _, issue := range matched {
+		ni := &NotificationInfo{
+			Owner:     repo.Owner,
+			AvatarURL: c.repo.avatarURL,
+			RepoName:  repo.Name,
+			RepoURL:   c.client.RepoURL(repo.Owner, repo.Name),
+			Current:   strconv.FormatInt(issue.GetID(), 10),
+			Prev:      prev,
+			Link:      issue.GetHTMLURL(),
+			Title:     issue.GetTitle(),
+			Body:      issue.GetBody(),
+			Target:    repo.Target,
+			CreatedAt: issue.GetCreatedAt(),
+			Extra:     issueExtra(issue),
+		}
+		c.notifiers.Notify(ni)
+		prev = ni.Current
 	}
-	c.notifiers.Notify(ni)
 
 	return nil
 }
 
-// Run checks latest pr.
+// Run checks new prs.
 func (c *PRChecker) Run() error {
 	repo := &lmdb.Repo{
+		Host:   storeHost(c.client),
 		Owner:  c.repo.Owner,
 		Name:   c.repo.Name,
 		Target: TargetPR,
 	}
-	if err := repo.Read(); err != nil {
+	if err := repo.Read(c.store); err != nil {
 		c.notifiers.Error(err)
 		return err
 	}
 
-	pr, err := github.LatestPRIssue(context.Background(), repo.Owner, repo.Name)
+	match, query, err := issueMatcher(c.repo, TargetPR)
 	if err != nil {
-		if err != github.ErrNotFound {
-			c.notifiers.Error(err)
-		}
+		c.notifiers.Error(err)
+		return err
+	}
+
+	current, parseErr := strconv.ParseInt(repo.Current, 10, 64)
+	prs, done, err := c.client.PRIssuesUntil(context.Background(), repo.Owner, repo.Name, query, fetchLimit(c.repo, repo), func(pr *gh.Issue) bool {
+		return parseErr == nil && current >= pr.GetID()
+	})
+	// nothing has changed since the last check.
+	if err == github.ErrNotModified {
+		return nil
+	}
+	if err != nil {
+		reportError(c.notifiers, err)
 		return err
 	}
 
 	// has new pr?
-	current, err := strconv.ParseInt(repo.Current, 10, 64)
-	if err == nil && current >= pr.GetID() {
+	if len(prs) == 0 {
+		done()
 		return nil
 	}
+	reportOverflow(c.notifiers, c.repo, repo, len(prs))
+
+	// pick the oldest limit prs matching the filter before moving the cursor.
+	// the cursor moves past unmatched prs too, and newer ones are left to the following checks.
+	var matched []*gh.Issue
+	next := len(prs)
+	for next > 0 && len(matched) < c.repo.Limit {
+		next--
+		if match(prs[next]) {
+			matched = append(matched, prs[next])
+		}
+	}
 
 	prev := repo.Current
-	repo.Current = strconv.FormatInt(pr.GetID(), 10)
-	if err := repo.Write(); err != nil {
+	repo.Current = strconv.FormatInt(prs[next].GetID(), 10)
+	if err := repo.Write(c.store); err != nil {
 		c.notifiers.Error(err)
 		return err
 	}
+	if next == 0 {
+		done()
+	}
 
-	ni := &NotificationInfo{
-		Owner:     repo.Owner,
-		AvatarURL: c.repo.avatarURL,
-		RepoName:  repo.Name,
-		Current:   repo.Current,
-		Prev:      prev,
-		Link:      pr.PullRequestLinks.GetHTMLURL(),
-		Title:     pr.GetTitle(),
-		Body:      pr.GetBody(),
-		Target:    repo.Target,
+	// notify from the oldest one.
+	for _, pr := range matched {
+		ni := &NotificationInfo{
+			Owner:     repo.Owner,
+			AvatarURL: c.repo.avatarURL,
+			RepoName:  repo.Name,
+			RepoURL:   c.client.RepoURL(repo.Owner, repo.Name),
+			Current:   strconv.FormatInt(pr.GetID(), 10),
+			Prev:      prev,
+			Link:      pr.PullRequestLinks.GetHTMLURL(),
+			Title:     pr.GetTitle(),
+			Body:      pr.GetBody(),
+			Target:    repo.Target,
+			CreatedAt: pr.GetCreatedAt(),
+			Extra:     issueExtra(pr),
+		}
+		c.notifiers.Notify(ni)
+		prev = ni.Current
 	}
-	c.notifiers.Notify(ni)
 
 	return nil
 }
 
-// Run checks latest tag.
+// Run checks state changes of pull requests.
+func (c *PRStateChecker) Run() error {
+	errNotifiers := c.errorNotifiers()
+	repo := &lmdb.Repo{
+		Host:   storeHost(c.client),
+		Owner:  c.repo.Owner,
+		Name:   c.repo.Name,
+		Target: targetPRState,
+	}
+	if err := repo.Read(c.store); err != nil {
+		errNotifiers.Error(err)
+		return err
+	}
+
+	matches := map[string]func(*gh.Issue) bool{}
+	for target := range c.notifiers {
+		match, _, err := issueMatcher(c.repo, target)
+		if err != nil {
+			errNotifiers.Error(err)
+			return err
+		}
+		matches[target] = match
+	}
+
+	// the cursor is updated time of the last updated pull request.
+	// at first check, states of pull requests are only stored.
+	since, parseErr := time.Parse(time.RFC3339, repo.Current)
+	first := parseErr != nil
+	prs, done, err := c.client.PullRequestsUpdatedUntil(context.Background(), repo.Owner, repo.Name, maxUpdatedPRs, func(pr *github.PullRequest) bool {
+		// pull requests updated at the same second as the cursor are checked again.
+		return !first && pr.GetUpdatedAt().Before(since)
+	})
+	// nothing has changed since the last check.
+	if err == github.ErrNotModified {
+		return nil
+	}
+	if err != nil {
+		reportError(errNotifiers, err)
+		return err
+	}
+
+	// has updated pr?
+	if len(prs) == 0 {
+		done()
+		return nil
+	}
+
+	if !first && len(prs) >= maxUpdatedPRs {
+		errNotifiers.Error(fmt.Errorf("(%s/%s) %d or more pull requests updated since the last check, older ones are skipped", repo.Owner, repo.Name, len(prs)))
+	}
+
+	type change struct {
+		target string
+		info   *NotificationInfo
+	}
+	var changes []change
+	var states []*lmdb.PullRequest
+	// check from the oldest one, until limit changes are found.
+	// newer ones are left to the following checks.
+	next := len(prs)
+	for next > 0 && (first || len(changes) < c.repo.Limit) {
+		next--
+		pr := prs[next]
+		state := &lmdb.PullRequest{
+			Host:   repo.Host,
+			Owner:  repo.Owner,
+			Name:   repo.Name,
+			Number: pr.GetNumber(),
+		}
+		if err := state.Read(c.store); err != nil {
+			errNotifiers.Error(err)
+			return err
+		}
+
+		if !first {
+			target, at := prTransition(state, pr, since)
+			if _, ok := c.notifiers[target]; ok && matches[target](issueOf(pr)) {
+				prev := state.State
+				if state.Draft {
+					prev = "draft"
+				}
+				extra := issueExtra(issueOf(pr))
+				extra["base"] = pr.GetBase().GetRef()
+				changes = append(changes, change{
+					target: target,
+					info: &NotificationInfo{
+						Owner:     repo.Owner,
+						AvatarURL: c.repo.avatarURL,
+						RepoName:  repo.Name,
+						RepoURL:   c.client.RepoURL(repo.Owner, repo.Name),
+						Current:   strconv.Itoa(pr.GetNumber()),
+						Prev:      prev,
+						Link:      pr.GetHTMLURL(),
+						Title:     pr.GetTitle(),
+						Body:      pr.GetBody(),
+						Target:    target,
+						CreatedAt: at,
+						Extra:     extra,
+					},
+				})
+			}
+		}
+
+		state.State = pr.GetState()
+		state.Draft = pr.GetDraft()
+		states = append(states, state)
+	}
+
+	// notify from the oldest one.
+	for _, ch := range changes {
+		c.notifiers[ch.target].Notify(ch.info)
+	}
+
+	// states and the cursor are saved after notifying, so that changes are not lost if notifying is interrupted.
+	for _, state := range states {
+		if err := state.Write(c.store); err != nil {
+			errNotifiers.Error(err)
+			return err
+		}
+	}
+	repo.Current = prs[next].GetUpdatedAt().UTC().Format(time.RFC3339)
+	if err := repo.Write(c.store); err != nil {
+		errNotifiers.Error(err)
+		return err
+	}
+	if next == 0 {
+		done()
+	}
+
+	return nil
+}
+
+// errorNotifiers returns notifiers of all targets without duplicates, to report errors to.
+func (c *PRStateChecker) errorNotifiers() notifiers {
+	ns := notifiers{}
+	for _, target := range []string{TargetPRMerged, TargetPRClosed, TargetPRReopened, TargetPRReady} {
+		for _, n := range c.notifiers[target] {
+			if !containsNotifier(ns, n) {
+				ns = append(ns, n)
+			}
+		}
+	}
+	return ns
+}
+
+// containsNotifier reports whether ns has n.
+func containsNotifier(ns notifiers, n Notifier) bool {
+	// comparing notifiers of uncomparable type panics.
+	if !reflect.TypeOf(n).Comparable() {
+		return false
+	}
+	for _, m := range ns {
+		if m == n {
+			return true
+		}
+	}
+	return false
+}
+
+// prTransition returns target of state change of pr from prev and when it happened.
+// it returns empty target if state is not changed.
+// if pr has never been seen, only that it was closed since the last check is known.
+func prTransition(prev *lmdb.PullRequest, pr *github.PullRequest, since time.Time) (string, time.Time) {
+	closed := pr.GetState() == "closed"
+	switch {
+	case prev.State == "":
+		if !closed || pr.GetClosedAt().Before(since) {
+			return "", time.Time{}
+		}
+		if pr.MergedAt != nil {
+			return TargetPRMerged, pr.GetMergedAt()
+		}
+		return TargetPRClosed, pr.GetClosedAt()
+	case prev.State == "open" && closed:
+		if pr.MergedAt != nil {
+			return TargetPRMerged, pr.GetMergedAt()
+		}
+		return TargetPRClosed, pr.GetClosedAt()
+	case prev.State == "closed" && !closed:
+		return TargetPRReopened, pr.GetUpdatedAt()
+	case prev.Draft && !pr.GetDraft() && !closed:
+		return TargetPRReady, pr.GetUpdatedAt()
+	}
+	return "", time.Time{}
+}
+
+// issueOf converts pr into issue to filter it as issue.
+func issueOf(pr *github.PullRequest) *gh.Issue {
+	issue := &gh.Issue{
+		Number: pr.Number,
+		Title:  pr.Title,
+		User:   pr.User,
+	}
+	for _, l := range pr.Labels {
+		if l != nil {
+			issue.Labels = append(issue.Labels, *l)
+		}
+	}
+	return issue
+}
+
+// Run checks new tags.
 func (c *TagChecker) Run() error {
 	repo := &lmdb.Repo{
+		Host:   storeHost(c.client),
 		Owner:  c.repo.Owner,
 		Name:   c.repo.Name,
 		Target: TargetTag,
 	}
-	if err := repo.Read(); err != nil {
+	if err := repo.Read(c.store); err != nil {
 		c.notifiers.Error(err)
 		return err
 	}
-
-	tag, err := github.LatestTag(context.Background(), repo.Owner, repo.Name)
+	filter, err := newVersionFilter(c.repo)
 	if err != nil {
-		if err != github.ErrNotFound {
-			c.notifiers.Error(err)
+		c.notifiers.Error(err)
+		return err
+	}
+
+	// github doesn't list tags in version order, so tags listed after the current one may be higher.
+	// all tags are scanned up to maxScannedTags instead of stopping at the current one, and the highest ones are picked.
+	scanned := 0
+	newTags, done, err := c.client.TagsUntil(context.Background(), repo.Owner, repo.Name, maxScannedTags, func(tag *gh.RepositoryTag) bool {
+		if !filter.match(tag.GetName(), isPrerelease(tag.GetName()), false) {
+			return false
 		}
+		return repo.Current == "" || version.CompareSimple(repo.Current, tag.GetName()) < 0
+	}, func(*gh.RepositoryTag) bool {
+		scanned++
+		return scanned > maxScannedTags
+	})
+	// nothing has changed since the last check.
+	if err == github.ErrNotModified {
+		return nil
+	}
+	if err != nil {
+		reportError(c.notifiers, err)
 		return err
 	}
+	if scanned > maxScannedTags {
+		c.notifiers.Error(fmt.Errorf("(%s/%s) more than %d tags, tags listed after them are not checked", repo.Owner, repo.Name, maxScannedTags))
+	}
 
 	// has new tag?
-	if repo.Current != "" && version.CompareSimple(repo.Current, tag.GetName()) >= 0 {
+	if len(newTags) == 0 {
+		done()
 		return nil
 	}
 
+	sort.SliceStable(newTags, func(i, j int) bool {
+		return version.CompareSimple(newTags[i].GetName(), newTags[j].GetName()) < 0
+	})
+	// only the highest tag is notified at first check.
+	// otherwise the lowest limit tags are notified, and higher ones are left to the following checks.
+	higher := 0
+	if repo.Current == "" {
+		newTags = newTags[len(newTags)-1:]
+	} else if len(newTags) > c.repo.Limit {
+		higher = len(newTags) - c.repo.Limit
+		newTags = newTags[:c.repo.Limit]
+	}
+
 	prev := repo.Current
-	repo.Current = tag.GetName()
-	if err := repo.Write(); err != nil {
+	repo.Current = newTags[len(newTags)-1].GetName()
+	if err := repo.Write(c.store); err != nil {
 		c.notifiers.Error(err)
 		return err
 	}
+	if higher == 0 {
+		done()
+	}
 
-	ni := &NotificationInfo{
-		Owner:     repo.Owner,
-		AvatarURL: c.repo.avatarURL,
-		RepoName:  repo.Name,
-		Current:   repo.Current,
-		Prev:      prev,
-		Link:      fmt.Sprintf("https://github.com/%s/%s/tags", repo.Owner, repo.Name),
-		Title:     tag.GetName(),
-		Body:      "",
-		Target:    repo.Target,
+	for _, tag := range newTags {
+		if !filter.bumped(prev, tag.GetName()) {
+			prev = tag.GetName()
+			continue
+		}
+		ni := &NotificationInfo{
+			Owner:     repo.Owner,
+			AvatarURL: c.repo.avatarURL,
+			RepoName:  repo.Name,
+			RepoURL:   c.client.RepoURL(repo.Owner, repo.Name),
+			Current:   tag.GetName(),
+			Prev:      prev,
+			Link:      c.client.RepoURL(repo.Owner, repo.Name) + "/tags",
+			Title:     tag.GetName(),
+			Body:      "",
+			Target:    repo.Target,
+			Extra: map[string]string{
+				"sha": tag.GetCommit().GetSHA(),
+			},
+		}
+		c.notifiers.Notify(ni)
+		prev = ni.Current
 	}
-	c.notifiers.Notify(ni)
 
 	return nil
 }
+
+// issueExtra returns extra fields of issue or pr for templates.
+func issueExtra(issue *gh.Issue) map[string]string {
+	labels := make([]string, 0, len(issue.Labels))
+	for _, l := range issue.Labels {
+		labels = append(labels, l.GetName())
+	}
+	return map[string]string{
+		"number": strconv.Itoa(issue.GetNumber()),
+		"author": issue.GetUser().GetLogin(),
+		"labels": strings.Join(labels, ","),
+	}
+}
+
+// matchPaths reports whether any of files is under or matches (as glob) any of paths.
+func matchPaths(paths []string, files []string) bool {
+	for _, p := range paths {
+		dir := strings.TrimSuffix(p, "/") + "/"
+		for _, f := range files {
+			if f == p || strings.HasPrefix(f, dir) {
+				return true
+			}
+			if ok, _ := path.Match(p, f); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reportError notifies error from github.
+// rate limit is notified only once until it is reset, instead of by every checker.
+func reportError(ns notifiers, err error) {
+	if err == github.ErrNotFound {
+		return
+	}
+	if e, ok := err.(*github.RateLimitError); ok && !e.First {
+		return
+	}
+	ns.Error(err)
+}
+
+// fetchLimit returns how many items are fetched at once.
+// at first check, only the latest item is fetched because there is no cursor to page back to.
+func fetchLimit(config *RepoConfig, repo *lmdb.Repo) int {
+	if repo.Current == "" {
+		return 1
+	}
+	if config.Limit > maxBacklog {
+		return config.Limit
+	}
+	return maxBacklog
+}
+
+// reportOverflow reports that items older than listed ones are skipped if listing reached the fetch limit.
+// it must be called before the cursor is moved.
+func reportOverflow(ns notifiers, config *RepoConfig, repo *lmdb.Repo, listed int) {
+	if repo.Current == "" || listed < fetchLimit(config, repo) {
+		return
+	}
+	ns.Error(fmt.Errorf("(%s/%s) %d or more new %ss since the last check, older ones are skipped", repo.Owner, repo.Name, listed, repo.Target))
+}
@@ -0,0 +1,231 @@
+package watchcat
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gh "github.com/google/go-github/github"
+	"github.com/kudohamu/watchcat/internal/github"
+	"github.com/kudohamu/watchcat/internal/lmdb"
+)
+
+func TestMatchPaths(t *testing.T) {
+	cases := []struct {
+		paths []string
+		files []string
+		want  bool
+	}{
+		{[]string{"src/runtime"}, []string{"src/runtime/proc.go"}, true},
+		{[]string{"src/runtime/"}, []string{"src/runtime/proc.go"}, true},
+		{[]string{"src/runtime"}, []string{"src/runtime2/proc.go"}, false},
+		{[]string{"README.md"}, []string{"README.md"}, true},
+		{[]string{"*.md"}, []string{"README.md"}, true},
+		{[]string{"*.md"}, []string{"doc/README.md"}, false},
+		{[]string{"doc/*.md"}, []string{"doc/README.md"}, true},
+		{[]string{"api", "doc"}, []string{"src/main.go", "doc/install.md"}, true},
+		{[]string{"api"}, nil, false},
+	}
+	for _, c := range cases {
+		if got := matchPaths(c.paths, c.files); got != c.want {
+			t.Errorf("matchPaths(%q, %q) = %v, want %v", c.paths, c.files, got, c.want)
+		}
+	}
+}
+
+func TestPRTransition(t *testing.T) {
+	since := time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC)
+	before := since.Add(-time.Hour)
+	after := since.Add(time.Hour)
+	updated := since.Add(2 * time.Hour)
+
+	pr := func(state string, draft bool, closedAt *time.Time, mergedAt *time.Time) *github.PullRequest {
+		return &github.PullRequest{
+			PullRequest: gh.PullRequest{
+				State:     &state,
+				ClosedAt:  closedAt,
+				MergedAt:  mergedAt,
+				UpdatedAt: &updated,
+			},
+			Draft: &draft,
+		}
+	}
+
+	cases := []struct {
+		name   string
+		prev   *lmdb.PullRequest
+		pr     *github.PullRequest
+		target string
+		at     time.Time
+	}{
+		{"unseen open", &lmdb.PullRequest{}, pr("open", false, nil, nil), "", time.Time{}},
+		{"unseen merged", &lmdb.PullRequest{}, pr("closed", false, &after, &after), TargetPRMerged, after},
+		{"unseen closed", &lmdb.PullRequest{}, pr("closed", false, &after, nil), TargetPRClosed, after},
+		{"unseen closed before since", &lmdb.PullRequest{}, pr("closed", false, &before, &before), "", time.Time{}},
+		{"merged", &lmdb.PullRequest{State: "open"}, pr("closed", false, &after, &after), TargetPRMerged, after},
+		{"closed", &lmdb.PullRequest{State: "open"}, pr("closed", false, &after, nil), TargetPRClosed, after},
+		{"draft closed", &lmdb.PullRequest{State: "open", Draft: true}, pr("closed", true, &after, nil), TargetPRClosed, after},
+		{"reopened", &lmdb.PullRequest{State: "closed"}, pr("open", false, nil, nil), TargetPRReopened, updated},
+		{"ready", &lmdb.PullRequest{State: "open", Draft: true}, pr("open", false, nil, nil), TargetPRReady, updated},
+		{"still draft", &lmdb.PullRequest{State: "open", Draft: true}, pr("open", true, nil, nil), "", time.Time{}},
+		{"converted to draft", &lmdb.PullRequest{State: "open"}, pr("open", true, nil, nil), "", time.Time{}},
+		{"still open", &lmdb.PullRequest{State: "open"}, pr("open", false, nil, nil), "", time.Time{}},
+		{"still closed", &lmdb.PullRequest{State: "closed"}, pr("closed", false, &after, &after), "", time.Time{}},
+	}
+	for _, c := range cases {
+		target, at := prTransition(c.prev, c.pr, since)
+		if target != c.target || !at.Equal(c.at) {
+			t.Errorf("%s: got %q at %s, want %q at %s", c.name, target, at, c.target, c.at)
+		}
+	}
+}
+
+// fakeGitHub serves json bodies by path of github api, with ETag of the body.
+type fakeGitHub struct {
+	bodies map[string]string
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, ok := f.bodies[strings.TrimPrefix(r.URL.Path, "/api/v3")]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"Not Found"}`)
+		return
+	}
+	etag := fmt.Sprintf(`"%x"`, len(body))
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	fmt.Fprint(w, body)
+}
+
+// connectFake connects to fake github api with memory store, and stores cursor of target.
+func connectFake(t *testing.T, fake *fakeGitHub, target string, current string) (*github.Client, lmdb.Store, func()) {
+	srv := httptest.NewServer(fake)
+	store := lmdb.NewMemoryStore()
+	client, err := github.Connect(&github.ConnectOption{BaseURL: srv.URL, Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &lmdb.Repo{Host: storeHost(client), Owner: "o", Name: "n", Target: target, Current: current}
+	if err := repo.Write(store); err != nil {
+		t.Fatal(err)
+	}
+	return client, store, srv.Close
+}
+
+// notified returns Current of notified infos.
+func notified(infos []*NotificationInfo) string {
+	currents := make([]string, 0, len(infos))
+	for _, info := range infos {
+		currents = append(currents, info.Current)
+	}
+	return strings.Join(currents, ",")
+}
+
+func TestTagCheckerScansPastLimit(t *testing.T) {
+	var tags []string
+	for i := 20; i >= 1; i-- {
+		tags = append(tags, fmt.Sprintf(`{"name":"weekly.2011-01-%02d"}`, i))
+	}
+	tags = append(tags, `{"name":"go1.13"}`, `{"name":"go1.12"}`, `{"name":"go1.11"}`)
+	fake := &fakeGitHub{bodies: map[string]string{
+		"/repos/o/n/tags": "[" + strings.Join(tags, ",") + "]",
+	}}
+	client, store, stop := connectFake(t, fake, TargetTag, "go1.12")
+	defer stop()
+
+	n := &recordNotifier{}
+	c := &TagChecker{
+		repo:      &RepoConfig{Owner: "o", Name: "n", Limit: 10, TagPattern: "^go1"},
+		notifiers: notifiers{n},
+		store:     store,
+		client:    client,
+	}
+	if err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got := notified(n.infos); got != "go1.13" || len(n.errs) != 0 {
+		t.Errorf("notified %q with errors %v", got, n.errs)
+	}
+}
+
+func TestReleaseCheckerOverLimit(t *testing.T) {
+	cases := []struct {
+		name     string
+		releases int
+		limit    int
+		checks   []string
+		errors   int
+	}{
+		{"within limit", 3, 10, []string{"v1.1,v1.2,v1.3", ""}, 0},
+		{"over limit", 15, 10, []string{"v1.1,v1.2,v1.3,v1.4,v1.5,v1.6,v1.7,v1.8,v1.9,v1.10", "v1.11,v1.12,v1.13,v1.14,v1.15", ""}, 0},
+		{"over backlog", maxBacklog + 5, 2, []string{"v1.6,v1.7"}, 1},
+	}
+	for _, c := range cases {
+		var releases []string
+		for i := c.releases; i >= 1; i-- {
+			releases = append(releases, fmt.Sprintf(`{"tag_name":"v1.%d"}`, i))
+		}
+		releases = append(releases, `{"tag_name":"v1.0.0"}`)
+		fake := &fakeGitHub{bodies: map[string]string{
+			"/repos/o/n/releases": "[" + strings.Join(releases, ",") + "]",
+		}}
+		client, store, stop := connectFake(t, fake, TargetRelease, "v1.0.0")
+
+		n := &recordNotifier{}
+		rc := &ReleaseChecker{
+			repo:      &RepoConfig{Owner: "o", Name: "n", Limit: c.limit},
+			notifiers: notifiers{n},
+			store:     store,
+			client:    client,
+		}
+		for i, want := range c.checks {
+			n.infos = nil
+			if err := rc.Run(); err != nil {
+				t.Fatalf("%s: %s", c.name, err)
+			}
+			if got := notified(n.infos); got != want {
+				t.Errorf("%s: check %d notified %q, want %q", c.name, i, got, want)
+			}
+		}
+		if len(n.errs) != c.errors {
+			t.Errorf("%s: %d errors, want %d: %v", c.name, len(n.errs), c.errors, n.errs)
+		}
+		stop()
+	}
+}
+
+func TestTagCheckerOverLimit(t *testing.T) {
+	var tags []string
+	for i := 1; i <= 15; i++ {
+		tags = append(tags, fmt.Sprintf(`{"name":"v1.%d"}`, i))
+	}
+	fake := &fakeGitHub{bodies: map[string]string{
+		"/repos/o/n/tags": "[" + strings.Join(tags, ",") + "]",
+	}}
+	client, store, stop := connectFake(t, fake, TargetTag, "v1.0")
+	defer stop()
+
+	n := &recordNotifier{}
+	c := &TagChecker{
+		repo:      &RepoConfig{Owner: "o", Name: "n", Limit: 10},
+		notifiers: notifiers{n},
+		store:     store,
+		client:    client,
+	}
+	for i, want := range []string{"v1.1,v1.2,v1.3,v1.4,v1.5,v1.6,v1.7,v1.8,v1.9,v1.10", "v1.11,v1.12,v1.13,v1.14,v1.15", ""} {
+		n.infos = nil
+		if err := c.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if got := notified(n.infos); got != want {
+			t.Errorf("check %d notified %q, want %q", i, got, want)
+		}
+	}
+}
@@ -17,20 +17,204 @@ func main() {
 		},
 		cli.StringFlag{
 			Name:  "notifiers",
-			Usage: "notification parties (std, slack)",
+			Usage: "notification parties (std, slack, mattermost, rocketchat, discord, teams, email, telegram, matrix, exec, feed, file, webhook)",
 		},
 		cli.StringFlag{
 			Name:  "slack_webhook_url",
 			Usage: "webhook url for notifying to slack",
 		},
+		cli.StringFlag{
+			Name:  "mattermost_webhook_url",
+			Usage: "incoming webhook url for notifying to mattermost",
+		},
+		cli.StringFlag{
+			Name:  "mattermost_username",
+			Usage: "username overriding the name of mattermost webhook",
+		},
+		cli.StringFlag{
+			Name:  "mattermost_icon_url",
+			Usage: "icon url overriding the icon of mattermost webhook",
+		},
+		cli.StringFlag{
+			Name:  "rocketchat_webhook_url",
+			Usage: "incoming webhook url for notifying to rocket.chat",
+		},
+		cli.StringFlag{
+			Name:  "rocketchat_alias",
+			Usage: "name overriding the name of rocket.chat webhook",
+		},
+		cli.StringFlag{
+			Name:  "rocketchat_avatar_url",
+			Usage: "avatar url overriding the avatar of rocket.chat webhook",
+		},
+		cli.StringFlag{
+			Name:  "discord_webhook_url",
+			Usage: "webhook url for notifying to discord",
+		},
+		cli.StringFlag{
+			Name:  "teams_webhook_url",
+			Usage: "incoming webhook or workflows url for notifying to microsoft teams",
+		},
+		cli.StringFlag{
+			Name:  "email_host",
+			Usage: "smtp server for notifying via email",
+		},
+		cli.IntFlag{
+			Name:  "email_port",
+			Usage: "port of smtp server (default: 465 for tls, otherwise 587)",
+		},
+		cli.StringFlag{
+			Name:  "email_tls",
+			Usage: "tls mode of smtp (starttls, tls, none) (default: starttls if supported)",
+		},
+		cli.StringFlag{
+			Name:  "email_username",
+			Usage: "username of smtp authentication",
+		},
+		cli.StringFlag{
+			Name:  "email_password",
+			Usage: "password of smtp authentication",
+		},
+		cli.StringFlag{
+			Name:  "email_auth",
+			Usage: "mechanism of smtp authentication (plain, login) (default: plain)",
+		},
+		cli.StringFlag{
+			Name:  "email_from",
+			Usage: "sender address of email",
+		},
+		cli.StringSliceFlag{
+			Name:  "email_to",
+			Usage: "recipient address of email",
+		},
+		cli.StringFlag{
+			Name:  "email_subject",
+			Usage: "subject template of email",
+		},
+		cli.StringFlag{
+			Name:  "telegram_token",
+			Usage: "bot token for notifying to telegram",
+		},
+		cli.StringFlag{
+			Name:  "telegram_chat_id",
+			Usage: "chat id of telegram to notify",
+		},
+		cli.StringFlag{
+			Name:  "telegram_api_url",
+			Usage: "url of telegram bot api (default: https://api.telegram.org)",
+		},
+		cli.StringFlag{
+			Name:  "matrix_homeserver_url",
+			Usage: "url of matrix homeserver for notifying to matrix room",
+		},
+		cli.StringFlag{
+			Name:  "matrix_token",
+			Usage: "access token of matrix user",
+		},
+		cli.StringFlag{
+			Name:  "matrix_room_id",
+			Usage: "id of matrix room to notify",
+		},
+		cli.StringFlag{
+			Name:  "exec_command",
+			Usage: "command run for each notification (separated by spaces)",
+		},
+		cli.StringFlag{
+			Name:  "exec_timeout",
+			Usage: "timeout of exec command (default: 1m)",
+		},
+		cli.IntFlag{
+			Name:  "exec_concurrency",
+			Usage: "max number of exec commands running at the same time (default: 1)",
+		},
+		cli.StringFlag{
+			Name:  "feed_addr",
+			Usage: "address to serve feeds on (default: localhost:8080)",
+		},
+		cli.IntFlag{
+			Name:  "feed_size",
+			Usage: "number of notifications kept in each feed (default: 50)",
+		},
+		cli.StringFlag{
+			Name:  "file_path",
+			Usage: "file path to append notifications as json lines",
+		},
+		cli.IntFlag{
+			Name:  "file_max_size",
+			Usage: "size in megabytes to rotate the file (default: 100)",
+		},
+		cli.IntFlag{
+			Name:  "file_max_backups",
+			Usage: "number of rotated files to keep (default: all)",
+		},
+		cli.StringFlag{
+			Name:  "webhook_url",
+			Usage: "url for posting notifications as json",
+		},
+		cli.StringFlag{
+			Name:  "webhook_secret",
+			Usage: "secret for signing json body with HMAC-SHA256",
+		},
+		cli.StringSliceFlag{
+			Name:  "webhook_header",
+			Usage: "header added to webhook request (Name: value)",
+		},
+		cli.StringFlag{
+			Name:  "webhook_timeout",
+			Usage: "timeout of webhook request (default: 20s)",
+		},
 		cli.StringFlag{
 			Name:  "interval, i",
 			Usage: "interval to check github (default: 30m)",
 		},
+		cli.StringFlag{
+			Name:  "digest",
+			Usage: "batch notifications into one digest per notifier, after each check (tick) or per window like 1h",
+		},
+		cli.IntFlag{
+			Name:  "max_retries",
+			Usage: "number of retries of failed notifications before giving up (default: 8)",
+		},
 		cli.StringFlag{
 			Name:  "token, t",
 			Usage: "github access token",
 		},
+		cli.Int64Flag{
+			Name:  "app_id",
+			Usage: "id of github app to authenticate as, instead of access token",
+		},
+		cli.Int64Flag{
+			Name:  "app_installation_id",
+			Usage: "installation id of github app",
+		},
+		cli.StringFlag{
+			Name:  "app_private_key",
+			Usage: "path of PEM file of github app's private key",
+		},
+		cli.StringFlag{
+			Name:  "github_url",
+			Usage: "url of github enterprise server (default: https://github.com/)",
+		},
+		cli.StringFlag{
+			Name:  "github_upload_url",
+			Usage: "upload url of github enterprise server (default: derived from github_url)",
+		},
+		cli.StringSliceFlag{
+			Name:  "host_token",
+			Usage: "access token for other github host which repositories override with github_url (host=token)",
+		},
+		cli.StringFlag{
+			Name:  "store",
+			Usage: "backend to store watching state (bolt, memory, file) (default: bolt)",
+		},
+		cli.StringFlag{
+			Name:  "db",
+			Usage: "file path of the state store (default: ~/.config/watchcat/watchcat.db)",
+		},
+		cli.StringFlag{
+			Name:  "namespace",
+			Usage: "namespace to isolate watching state from other watchers sharing the store",
+		},
 	}
 	app.Commands = []cli.Command{
 		cli.Command{
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/kudohamu/watchcat"
 	"github.com/urfave/cli"
@@ -18,7 +19,41 @@ func Watch(c *cli.Context) {
 		interval = "30m"
 	}
 
-	watcher := watchcat.New(conf, interval, accessToken)
+	hostTokens := map[string]string{}
+	for _, ht := range c.GlobalStringSlice("host_token") {
+		kv := strings.SplitN(ht, "=", 2)
+		if len(kv) != 2 {
+			panic(fmt.Errorf("invalid host_token: %s", ht))
+		}
+		hostTokens[kv[0]] = kv[1]
+	}
+
+	var app *watchcat.AppOption
+	if appID := c.GlobalInt64("app_id"); appID != 0 {
+		app = &watchcat.AppOption{
+			ID:             appID,
+			InstallationID: c.GlobalInt64("app_installation_id"),
+			PrivateKeyPath: c.GlobalString("app_private_key"),
+		}
+		if app.InstallationID == 0 {
+			panic(fmt.Errorf("not specified `app_installation_id` flag"))
+		}
+		if app.PrivateKeyPath == "" {
+			panic(fmt.Errorf("not specified `app_private_key` flag"))
+		}
+	}
+
+	watcher := watchcat.New(conf, interval, accessToken, watchcat.Option{
+		Store:           c.GlobalString("store"),
+		DBPath:          c.GlobalString("db"),
+		Namespace:       c.GlobalString("namespace"),
+		GitHubURL:       c.GlobalString("github_url"),
+		GitHubUploadURL: c.GlobalString("github_upload_url"),
+		HostTokens:      hostTokens,
+		App:             app,
+		Digest:          c.GlobalString("digest"),
+		MaxRetries:      c.GlobalInt("max_retries"),
+	})
 
 	for _, notifier := range strings.Split(c.GlobalString("notifiers"), ",") {
 		switch notifier {
@@ -32,6 +67,158 @@ func Watch(c *cli.Context) {
 			watcher.AddNotifier(&watchcat.SlackNotifier{
 				WebhookURL: webhookURL,
 			})
+		case "mattermost":
+			webhookURL := c.GlobalString("mattermost_webhook_url")
+			if webhookURL == "" {
+				panic(fmt.Errorf("not specified `mattermost_webhook_url` flag"))
+			}
+			watcher.AddNotifier(&watchcat.MattermostNotifier{
+				WebhookURL: webhookURL,
+				Username:   c.GlobalString("mattermost_username"),
+				IconURL:    c.GlobalString("mattermost_icon_url"),
+			})
+		case "rocketchat":
+			webhookURL := c.GlobalString("rocketchat_webhook_url")
+			if webhookURL == "" {
+				panic(fmt.Errorf("not specified `rocketchat_webhook_url` flag"))
+			}
+			watcher.AddNotifier(&watchcat.RocketChatNotifier{
+				WebhookURL: webhookURL,
+				Alias:      c.GlobalString("rocketchat_alias"),
+				AvatarURL:  c.GlobalString("rocketchat_avatar_url"),
+			})
+		case "discord":
+			webhookURL := c.GlobalString("discord_webhook_url")
+			if webhookURL == "" {
+				panic(fmt.Errorf("not specified `discord_webhook_url` flag"))
+			}
+			watcher.AddNotifier(&watchcat.DiscordNotifier{
+				WebhookURL: webhookURL,
+			})
+		case "teams":
+			webhookURL := c.GlobalString("teams_webhook_url")
+			if webhookURL == "" {
+				panic(fmt.Errorf("not specified `teams_webhook_url` flag"))
+			}
+			watcher.AddNotifier(&watchcat.TeamsNotifier{
+				WebhookURL: webhookURL,
+			})
+		case "email":
+			host := c.GlobalString("email_host")
+			if host == "" {
+				panic(fmt.Errorf("not specified `email_host` flag"))
+			}
+			from := c.GlobalString("email_from")
+			if from == "" {
+				panic(fmt.Errorf("not specified `email_from` flag"))
+			}
+			to := c.GlobalStringSlice("email_to")
+			if len(to) == 0 {
+				panic(fmt.Errorf("not specified `email_to` flag"))
+			}
+			watcher.AddNotifier(&watchcat.EmailNotifier{
+				Host:     host,
+				Port:     c.GlobalInt("email_port"),
+				TLS:      c.GlobalString("email_tls"),
+				Username: c.GlobalString("email_username"),
+				Password: c.GlobalString("email_password"),
+				Auth:     c.GlobalString("email_auth"),
+				From:     from,
+				To:       to,
+				Subject:  c.GlobalString("email_subject"),
+			})
+		case "telegram":
+			token := c.GlobalString("telegram_token")
+			if token == "" {
+				panic(fmt.Errorf("not specified `telegram_token` flag"))
+			}
+			chatID := c.GlobalString("telegram_chat_id")
+			if chatID == "" {
+				panic(fmt.Errorf("not specified `telegram_chat_id` flag"))
+			}
+			watcher.AddNotifier(&watchcat.TelegramNotifier{
+				Token:  token,
+				ChatID: chatID,
+				APIURL: c.GlobalString("telegram_api_url"),
+			})
+		case "matrix":
+			homeserverURL := c.GlobalString("matrix_homeserver_url")
+			if homeserverURL == "" {
+				panic(fmt.Errorf("not specified `matrix_homeserver_url` flag"))
+			}
+			token := c.GlobalString("matrix_token")
+			if token == "" {
+				panic(fmt.Errorf("not specified `matrix_token` flag"))
+			}
+			roomID := c.GlobalString("matrix_room_id")
+			if roomID == "" {
+				panic(fmt.Errorf("not specified `matrix_room_id` flag"))
+			}
+			watcher.AddNotifier(&watchcat.MatrixNotifier{
+				HomeserverURL: homeserverURL,
+				AccessToken:   token,
+				RoomID:        roomID,
+			})
+		case "exec":
+			command := strings.Fields(c.GlobalString("exec_command"))
+			if len(command) == 0 {
+				panic(fmt.Errorf("not specified `exec_command` flag"))
+			}
+			var timeout time.Duration
+			if t := c.GlobalString("exec_timeout"); t != "" {
+				var err error
+				timeout, err = time.ParseDuration(t)
+				if err != nil {
+					panic(fmt.Errorf("invalid exec_timeout: %s", t))
+				}
+			}
+			watcher.AddNotifier(&watchcat.ExecNotifier{
+				Command:     command,
+				Timeout:     timeout,
+				Concurrency: c.GlobalInt("exec_concurrency"),
+			})
+		case "feed":
+			watcher.AddNotifier(&watchcat.FeedNotifier{
+				Addr: c.GlobalString("feed_addr"),
+				Size: c.GlobalInt("feed_size"),
+			})
+		case "file":
+			path := c.GlobalString("file_path")
+			if path == "" {
+				panic(fmt.Errorf("not specified `file_path` flag"))
+			}
+			watcher.AddNotifier(&watchcat.FileNotifier{
+				Path:       path,
+				MaxSize:    int64(c.GlobalInt("file_max_size")) * 1024 * 1024,
+				MaxBackups: c.GlobalInt("file_max_backups"),
+			})
+		case "webhook":
+			webhookURL := c.GlobalString("webhook_url")
+			if webhookURL == "" {
+				panic(fmt.Errorf("not specified `webhook_url` flag"))
+			}
+			headers := map[string]string{}
+			for _, h := range c.GlobalStringSlice("webhook_header") {
+				kv := strings.SplitN(h, ":", 2)
+				if len(kv) != 2 {
+					panic(fmt.Errorf("invalid webhook_header: %s", h))
+				}
+				headers[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+			}
+			var timeout time.Duration
+			if t := c.GlobalString("webhook_timeout"); t != "" {
+				var err error
+				timeout, err = time.ParseDuration(t)
+				if err != nil {
+					panic(fmt.Errorf("invalid webhook_timeout: %s", t))
+				}
+			}
+			watcher.AddNotifier(&watchcat.WebhookNotifier{
+				URL:     webhookURL,
+				Headers: headers,
+				Secret:  c.GlobalString("webhook_secret"),
+				Timeout: timeout,
+			})
 		default:
 			panic(fmt.Errorf("invalid notifier: %s", notifier))
 		}
@@ -0,0 +1,232 @@
+package watchcat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kudohamu/petelgeuse"
+	"github.com/kudohamu/watchcat/internal/lmdb"
+)
+
+// TargetDigest is the target of notification which groups notifications in digest mode.
+const TargetDigest = "digest"
+
+// DigestTick sends digest after each check instead of after fixed window.
+const DigestTick = "tick"
+
+const bktDigest = "digest"
+
+// digestNotifier buffers notifications in the store and sends them as one digest.
+// pending notifications survive restarts, so they are never lost before the cursor advances.
+// errors are sent immediately.
+type digestNotifier struct {
+	mu sync.Mutex
+	// notifier sends digests, which is replaced at every check to follow templates in config.
+	notifier Notifier
+	name     string
+	store    lmdb.Store
+}
+
+// Notify buffers notification until flush.
+func (n *digestNotifier) Notify(info *NotificationInfo) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	pending, err := n.pending()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(append(pending, info))
+	if err != nil {
+		return err
+	}
+	return n.store.Put(bktDigest, n.name, data)
+}
+
+// Error notifies error immediately.
+func (n *digestNotifier) Error(err error) error {
+	return n.current().Error(err)
+}
+
+// flush sends buffered notifications as one digest.
+// failed digest is left to the retry queue, so buffered notifications are removed anyway.
+func (n *digestNotifier) flush() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	pending, err := n.pending()
+	if err != nil || len(pending) == 0 {
+		return err
+	}
+	err = n.notifier.Notify(digestOf(pending))
+	if derr := n.store.Delete(bktDigest, n.name); derr != nil && err == nil {
+		err = derr
+	}
+	return err
+}
+
+// pending returns buffered notifications, which must be called with mu locked.
+func (n *digestNotifier) pending() ([]*NotificationInfo, error) {
+	data, err := n.store.Get(bktDigest, n.name)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var infos []*NotificationInfo
+	if err := json.Unmarshal(data, &infos); err != nil {
+		return nil, err
+	}
+	return infos, nil
+}
+
+func (n *digestNotifier) current() Notifier {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.notifier
+}
+
+// digestOf wraps notifier named name, which sends digests through inner, into digestNotifier.
+// wrappers are kept across checks by notifier, and inner is replaced with the latest one.
+// machine readable notifiers (webhook, exec, file and feed) still receive every notification.
+func (w *Watcher) digestOf(n Notifier, name string, inner Notifier) Notifier {
+	if w.digest == "" {
+		return inner
+	}
+	switch notifierName(n) {
+	case "webhook", "exec", "file", "feed":
+		return inner
+	}
+
+	w.digestMu.Lock()
+	defer w.digestMu.Unlock()
+	if d, ok := w.digests[n]; ok && d.name == name {
+		d.mu.Lock()
+		d.notifier = inner
+		d.mu.Unlock()
+		return d
+	}
+	store := w.store
+	if store == nil {
+		store = lmdb.NewMemoryStore()
+	}
+	d := &digestNotifier{notifier: inner, name: name, store: store}
+	w.digests[n] = d
+	return d
+}
+
+// flushDigests sends digests of all notifiers.
+func (w *Watcher) flushDigests() {
+	w.digestMu.Lock()
+	ds := make([]*digestNotifier, 0, len(w.digests))
+	for _, d := range w.digests {
+		ds = append(ds, d)
+	}
+	w.digestMu.Unlock()
+
+	for _, d := range ds {
+		if err := d.flush(); err != nil {
+			d.Error(fmt.Errorf("failed to notify digest: %s", err))
+		}
+	}
+}
+
+// trackedTask marks done of wg when task finishes.
+type trackedTask struct {
+	petelgeuse.Task
+	wg *sync.WaitGroup
+}
+
+// Run runs task.
+func (t *trackedTask) Run() error {
+	defer t.wg.Done()
+	return t.Task.Run()
+}
+
+// digestOf groups notifications by repository and target.
+func digestOf(infos []*NotificationInfo) *NotificationInfo {
+	type group struct {
+		name    string
+		url     string
+		targets map[string][]*NotificationInfo
+	}
+	groups := map[string]*group{}
+	var names []string
+	for _, info := range infos {
+		name := fmt.Sprintf("%s/%s", info.Owner, info.RepoName)
+		g, ok := groups[name]
+		if !ok {
+			g = &group{
+				name:    name,
+				url:     info.RepoURL,
+				targets: map[string][]*NotificationInfo{},
+			}
+			groups[name] = g
+			names = append(names, name)
+		}
+		g.targets[info.Target] = append(g.targets[info.Target], info)
+	}
+	sort.Strings(names)
+
+	var body bytes.Buffer
+	for _, name := range names {
+		g := groups[name]
+		if g.url != "" {
+			fmt.Fprintf(&body, "**[%s](%s)**\n", g.name, g.url)
+		} else {
+			fmt.Fprintf(&body, "**%s**\n", g.name)
+		}
+		targets := make([]string, 0, len(g.targets))
+		for target := range g.targets {
+			targets = append(targets, target)
+		}
+		sort.Strings(targets)
+		for _, target := range targets {
+			items := g.targets[target]
+			fmt.Fprintf(&body, "* %s (%d): ", target, len(items))
+			for i, item := range items {
+				if i > 0 {
+					body.WriteString(", ")
+				}
+				title := truncate(item.Title, 80)
+				if target == TargetCommit {
+					title = strings.TrimSpace(shortSHA(item.Title) + " " + truncate(item.Extra["subject"], 80))
+				}
+				if item.Link != "" {
+					fmt.Fprintf(&body, "[%s](%s)", title, item.Link)
+				} else {
+					body.WriteString(title)
+				}
+			}
+			body.WriteString("\n")
+		}
+		body.WriteString("\n")
+	}
+
+	title := fmt.Sprintf("%d new events in %d repositories", len(infos), len(names))
+	return &NotificationInfo{
+		Owner:    "watchcat",
+		RepoName: TargetDigest,
+		Target:   TargetDigest,
+		Current:  strconv.Itoa(len(infos)),
+		Title:    title,
+		Body:     strings.TrimSpace(body.String()),
+		Extra: map[string]string{
+			"events":       strconv.Itoa(len(infos)),
+			"repositories": strconv.Itoa(len(names)),
+		},
+	}
+}
+
+// mrkdwnDigest converts body of digest into mrkdwn for slack and rocket.chat, which don't render github markdown.
+func mrkdwnDigest(info *NotificationInfo) *NotificationInfo {
+	if info.Target != TargetDigest {
+		return info
+	}
+	converted := *info
+	converted.Body = markdownToSlack(info.Body)
+	return &converted
+}
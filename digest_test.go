@@ -0,0 +1,156 @@
+package watchcat
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kudohamu/watchcat/internal/lmdb"
+)
+
+// recordNotifier records notifications and errors.
+type recordNotifier struct {
+	infos []*NotificationInfo
+	errs  []error
+}
+
+func (n *recordNotifier) Notify(info *NotificationInfo) error {
+	n.infos = append(n.infos, info)
+	return nil
+}
+
+func (n *recordNotifier) Error(err error) error {
+	n.errs = append(n.errs, err)
+	return nil
+}
+
+func TestDigestOf(t *testing.T) {
+	cases := []struct {
+		name  string
+		infos []*NotificationInfo
+		title string
+		body  string
+	}{
+		{
+			name: "one event",
+			infos: []*NotificationInfo{
+				{Owner: "golang", RepoName: "go", RepoURL: "https://github.com/golang/go", Target: TargetRelease, Title: "go1.12", Link: "https://github.com/golang/go/releases/tag/go1.12"},
+			},
+			title: "1 new events in 1 repositories",
+			body:  "**[golang/go](https://github.com/golang/go)**\n* release (1): [go1.12](https://github.com/golang/go/releases/tag/go1.12)",
+		},
+		{
+			name: "grouped by repository and target",
+			infos: []*NotificationInfo{
+				{Owner: "golang", RepoName: "go", Target: TargetRelease, Title: "go1.12"},
+				{Owner: "docker", RepoName: "cli", Target: TargetIssue, Title: "crash", Link: "l1"},
+				{Owner: "golang", RepoName: "go", Target: TargetCommit, Title: "0123456789abcdef", Extra: map[string]string{"subject": "runtime: fix"}},
+				{Owner: "golang", RepoName: "go", Target: TargetRelease, Title: "go1.11.6"},
+			},
+			title: "4 new events in 2 repositories",
+			body:  "**docker/cli**\n* issue (1): [crash](l1)\n\n**golang/go**\n* commit (1): 0123456 runtime: fix\n* release (2): go1.12, go1.11.6",
+		},
+	}
+	for _, c := range cases {
+		info := digestOf(c.infos)
+		if info.Target != TargetDigest || info.Title != c.title {
+			t.Errorf("%s: unexpected digest %q of %q", c.name, info.Title, info.Target)
+		}
+		if info.Body != c.body {
+			t.Errorf("%s: body = %q, want %q", c.name, info.Body, c.body)
+		}
+	}
+}
+
+func TestWatcherDigestOf(t *testing.T) {
+	store := lmdb.NewMemoryStore()
+	w := &Watcher{
+		digest:  DigestTick,
+		digests: map[Notifier]*digestNotifier{},
+		store:   store,
+	}
+	n := &recordNotifier{}
+	d := w.digestOf(n, "chat", n)
+	d.Notify(&NotificationInfo{Owner: "golang", RepoName: "go", Target: TargetRelease, Title: "go1.12"})
+	d.Notify(&NotificationInfo{Owner: "golang", RepoName: "go", Target: TargetTag, Title: "go1.12"})
+	if len(n.infos) != 0 {
+		t.Fatalf("notified before flush: %d", len(n.infos))
+	}
+	d.Error(errors.New("failed"))
+	if len(n.errs) != 1 {
+		t.Error("error is not notified immediately")
+	}
+
+	// the wrapper is kept across checks, and sends digests through the latest inner notifier.
+	inner := &recordNotifier{}
+	if w.digestOf(n, "chat", inner) != d {
+		t.Fatal("digest notifier is not kept")
+	}
+
+	// pending notifications are kept in the store, so they survive restarts.
+	restarted := &Watcher{
+		digest:  DigestTick,
+		digests: map[Notifier]*digestNotifier{},
+		store:   store,
+	}
+	if pending, err := restarted.digestOf(n, "chat", inner).(*digestNotifier).pending(); err != nil || len(pending) != 2 {
+		t.Fatalf("%d notifications are pending after restart: %v", len(pending), err)
+	}
+
+	w.flushDigests()
+	if len(n.infos) != 0 || len(inner.infos) != 1 || inner.infos[0].Current != "2" {
+		t.Fatalf("unexpected digest: %d %d", len(n.infos), len(inner.infos))
+	}
+	w.flushDigests()
+	if len(inner.infos) != 1 {
+		t.Error("empty digest is sent")
+	}
+
+	// machine readable notifiers receive every notification.
+	webhook := &WebhookNotifier{}
+	if w.digestOf(webhook, "hook", webhook) != webhook {
+		t.Error("webhook notifier is wrapped")
+	}
+	w.digest = ""
+	if w.digestOf(n, "chat", n) != n {
+		t.Error("notifier is wrapped without digest")
+	}
+}
+
+func TestDigestPayloads(t *testing.T) {
+	var payload string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		payload = string(data)
+	}))
+	defer srv.Close()
+
+	info := digestOf([]*NotificationInfo{
+		{Owner: "golang", RepoName: "go", Target: TargetRelease, Title: "go1.12"},
+	})
+	cases := []struct {
+		name     string
+		notifier Notifier
+	}{
+		{"teams", &TeamsNotifier{WebhookURL: srv.URL}},
+		{"discord", &DiscordNotifier{WebhookURL: srv.URL}},
+	}
+	for _, c := range cases {
+		payload = ""
+		if err := c.notifier.Notify(info); err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		var v interface{}
+		if err := json.Unmarshal([]byte(payload), &v); err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		// digests have no link, which must not be sent as empty url.
+		if strings.Contains(payload, `"url":""`) || strings.Contains(payload, `"icon_url":""`) || strings.Contains(payload, "Action.OpenUrl") || strings.Contains(payload, "]()") {
+			t.Errorf("%s: empty url is sent: %s", c.name, payload)
+		}
+	}
+}
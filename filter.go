@@ -0,0 +1,133 @@
+package watchcat
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	gh "github.com/google/go-github/github"
+	"github.com/kudohamu/watchcat/internal/github"
+)
+
+// IssueFilter selects issues and pull requests to notify.
+type IssueFilter struct {
+	// LabelsAny requires any of labels.
+	LabelsAny []string `toml:"labels_any"`
+	// LabelsAll requires all of labels.
+	LabelsAll []string `toml:"labels_all"`
+	// LabelsNone excludes issues having any of labels.
+	LabelsNone []string `toml:"labels_none"`
+	// Authors are logins of authors to notify. all authors are notified if it is empty.
+	Authors        []string `toml:"authors"`
+	ExcludeAuthors []string `toml:"exclude_authors"`
+	// ExcludeBots excludes issues opened by bots, like dependabot.
+	ExcludeBots bool `toml:"exclude_bots"`
+	// Title is the regular expression which titles must match.
+	Title string `toml:"title"`
+}
+
+// filterTargets are targets which filters can be applied to.
+var filterTargets = map[string]bool{
+	TargetIssue:      true,
+	TargetPR:         true,
+	TargetPRMerged:   true,
+	TargetPRClosed:   true,
+	TargetPRReopened: true,
+	TargetPRReady:    true,
+}
+
+// checkFilters returns error if filters of repo are keyed by targets which they can't be applied to.
+func checkFilters(repo *RepoConfig) error {
+	var unknown []string
+	for target := range repo.Filters {
+		if !filterTargets[target] {
+			unknown = append(unknown, target)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("(%s/%s) unknown targets of filters: %s", repo.Owner, repo.Name, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// issueMatcher returns function to select issues of target of repo,
+// and query to narrow issues listed by github with the filter.
+func issueMatcher(repo *RepoConfig, target string) (func(*gh.Issue) bool, *github.IssueQuery, error) {
+	f := repo.Filters[target]
+	if f == nil {
+		return func(*gh.Issue) bool { return true }, nil, nil
+	}
+
+	var title *regexp.Regexp
+	if f.Title != "" {
+		var err error
+		title, err = regexp.Compile(f.Title)
+		if err != nil {
+			return nil, nil, fmt.Errorf("(%s/%s) invalid title filter of %s: %s", repo.Owner, repo.Name, target, err)
+		}
+	}
+
+	// github lists issues having all of labels, and of one creator.
+	query := &github.IssueQuery{
+		Labels: f.LabelsAll,
+	}
+	if len(f.LabelsAny) == 1 {
+		query.Labels = append(append([]string{}, query.Labels...), f.LabelsAny[0])
+	}
+	if len(f.Authors) == 1 {
+		query.Creator = f.Authors[0]
+	}
+
+	match := func(issue *gh.Issue) bool {
+		labels := map[string]bool{}
+		for _, l := range issue.Labels {
+			labels[strings.ToLower(l.GetName())] = true
+		}
+		if len(f.LabelsAny) > 0 && !hasAny(labels, f.LabelsAny) {
+			return false
+		}
+		for _, l := range f.LabelsAll {
+			if !labels[strings.ToLower(l)] {
+				return false
+			}
+		}
+		if hasAny(labels, f.LabelsNone) {
+			return false
+		}
+
+		author := issue.GetUser().GetLogin()
+		if len(f.Authors) > 0 && !containsFold(f.Authors, author) {
+			return false
+		}
+		if containsFold(f.ExcludeAuthors, author) {
+			return false
+		}
+		if f.ExcludeBots && (issue.GetUser().GetType() == "Bot" || strings.HasSuffix(author, "[bot]")) {
+			return false
+		}
+
+		return title == nil || title.MatchString(issue.GetTitle())
+	}
+	return match, query, nil
+}
+
+// hasAny reports whether labels, which are lower cased, have any of names.
+func hasAny(labels map[string]bool, names []string) bool {
+	for _, n := range names {
+		if labels[strings.ToLower(n)] {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(ss []string, s string) bool {
+	for _, v := range ss {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package watchcat
+
+import (
+	"reflect"
+	"testing"
+
+	gh "github.com/google/go-github/github"
+	"github.com/kudohamu/watchcat/internal/github"
+)
+
+func testIssue(title string, login string, userType string, labels ...string) *gh.Issue {
+	issue := &gh.Issue{
+		Title: &title,
+		User:  &gh.User{Login: &login, Type: &userType},
+	}
+	for i := range labels {
+		issue.Labels = append(issue.Labels, gh.Label{Name: &labels[i]})
+	}
+	return issue
+}
+
+func TestIssueMatcher(t *testing.T) {
+	cases := []struct {
+		name   string
+		filter *IssueFilter
+		issue  *gh.Issue
+		want   bool
+	}{
+		{"no filter", nil, testIssue("a", "u", "User"), true},
+		{"labels any", &IssueFilter{LabelsAny: []string{"bug", "security"}}, testIssue("a", "u", "User", "Security"), true},
+		{"labels any missing", &IssueFilter{LabelsAny: []string{"bug", "security"}}, testIssue("a", "u", "User", "docs"), false},
+		{"labels all", &IssueFilter{LabelsAll: []string{"bug", "p1"}}, testIssue("a", "u", "User", "P1", "bug"), true},
+		{"labels all partial", &IssueFilter{LabelsAll: []string{"bug", "p1"}}, testIssue("a", "u", "User", "bug"), false},
+		{"labels none", &IssueFilter{LabelsNone: []string{"wontfix"}}, testIssue("a", "u", "User", "bug", "WontFix"), false},
+		{"authors", &IssueFilter{Authors: []string{"rsc", "ianlancetaylor"}}, testIssue("a", "RSC", "User"), true},
+		{"authors other", &IssueFilter{Authors: []string{"rsc"}}, testIssue("a", "u", "User"), false},
+		{"exclude authors", &IssueFilter{ExcludeAuthors: []string{"gopherbot"}}, testIssue("a", "gopherbot", "User"), false},
+		{"exclude bots by type", &IssueFilter{ExcludeBots: true}, testIssue("a", "renovate", "Bot"), false},
+		{"exclude bots by login", &IssueFilter{ExcludeBots: true}, testIssue("a", "dependabot[bot]", "User"), false},
+		{"exclude bots human", &IssueFilter{ExcludeBots: true}, testIssue("a", "u", "User"), true},
+		{"title", &IssueFilter{Title: "^(cmd|runtime)/"}, testIssue("runtime/pprof: fix race", "u", "User"), true},
+		{"title mismatch", &IssueFilter{Title: "^(cmd|runtime)/"}, testIssue("net/http: fix race", "u", "User"), false},
+	}
+	for _, c := range cases {
+		repo := &RepoConfig{Owner: "o", Name: "n", Filters: map[string]*IssueFilter{TargetIssue: c.filter}}
+		match, _, err := issueMatcher(repo, TargetIssue)
+		if err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		if got := match(c.issue); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestIssueMatcherQuery(t *testing.T) {
+	cases := []struct {
+		filter *IssueFilter
+		want   *github.IssueQuery
+	}{
+		{nil, nil},
+		{&IssueFilter{LabelsAll: []string{"bug", "p1"}}, &github.IssueQuery{Labels: []string{"bug", "p1"}}},
+		{&IssueFilter{LabelsAll: []string{"bug"}, LabelsAny: []string{"p1"}}, &github.IssueQuery{Labels: []string{"bug", "p1"}}},
+		{&IssueFilter{LabelsAny: []string{"bug", "p1"}}, &github.IssueQuery{}},
+		{&IssueFilter{Authors: []string{"rsc"}}, &github.IssueQuery{Creator: "rsc"}},
+		{&IssueFilter{Authors: []string{"rsc", "adg"}}, &github.IssueQuery{}},
+	}
+	for _, c := range cases {
+		repo := &RepoConfig{Owner: "o", Name: "n", Filters: map[string]*IssueFilter{TargetPR: c.filter}}
+		_, query, err := issueMatcher(repo, TargetPR)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(query, c.want) {
+			t.Errorf("query of %+v = %+v, want %+v", c.filter, query, c.want)
+		}
+	}
+}
+
+func TestIssueMatcherInvalid(t *testing.T) {
+	repo := &RepoConfig{Owner: "o", Name: "n", Filters: map[string]*IssueFilter{TargetIssue: {Title: "("}}}
+	if _, _, err := issueMatcher(repo, TargetIssue); err == nil {
+		t.Error("no error with invalid title")
+	}
+}
+
+func TestCheckFilters(t *testing.T) {
+	cases := []struct {
+		targets []string
+		valid   bool
+	}{
+		{nil, true},
+		{[]string{TargetIssue, TargetPR, TargetPRMerged, TargetPRClosed, TargetPRReopened, TargetPRReady}, true},
+		{[]string{"issues"}, false},
+		{[]string{TargetPR, TargetRelease}, false},
+	}
+	for _, c := range cases {
+		repo := &RepoConfig{Owner: "o", Name: "n", Filters: map[string]*IssueFilter{}}
+		for _, target := range c.targets {
+			repo.Filters[target] = &IssueFilter{}
+		}
+		if err := checkFilters(repo); (err == nil) != c.valid {
+			t.Errorf("checkFilters of %q = %v", c.targets, err)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package github
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// jwtLifetime is lifetime of jwt authenticating as github app. github accepts at most 10 minutes.
+const jwtLifetime = 9 * time.Minute
+
+// refreshMargin is how long before expiry installation token is refreshed.
+const refreshMargin = 5 * time.Minute
+
+// installationTokenSource mints installation access tokens of github app.
+type installationTokenSource struct {
+	apiURL         string
+	appID          int64
+	installationID int64
+	key            *rsa.PrivateKey
+	client         *http.Client
+}
+
+// newInstallationTokenSource creates token source which refreshes installation token before it expires.
+func newInstallationTokenSource(apiURL string, appID int64, installationID int64, privateKey []byte) (oauth2.TokenSource, error) {
+	key, err := parsePrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return oauth2.ReuseTokenSource(nil, &installationTokenSource{
+		apiURL:         apiURL,
+		appID:          appID,
+		installationID: installationID,
+		key:            key,
+		client:         &http.Client{Timeout: 20 * time.Second},
+	}), nil
+}
+
+// Token exchanges jwt of the app for a new installation token.
+func (s *installationTokenSource) Token() (*oauth2.Token, error) {
+	jwt, err := s.jwt(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	u := fmt.Sprintf("%sapp/installations/%d/access_tokens", s.apiURL, s.installationID)
+	req, err := http.NewRequest(http.MethodPost, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github.machine-man-preview+json")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("failed to create installation token: %s", res.Status)
+	}
+
+	var body struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	return &oauth2.Token{
+		AccessToken: body.Token,
+		TokenType:   "token",
+		Expiry:      body.ExpiresAt.Add(-refreshMargin),
+	}, nil
+}
+
+// jwt signs claims identifying the app with RS256.
+func (s *installationTokenSource) jwt(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+	})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]int64{
+		// allow clock drift between github.
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(jwtLifetime).Unix(),
+		"iss": s.appID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(base64.RawURLEncoding.EncodeToString(header))
+	buf.WriteByte('.')
+	buf.WriteString(base64.RawURLEncoding.EncodeToString(claims))
+
+	hashed := sha256.Sum256(buf.Bytes())
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return "", err
+	}
+	buf.WriteByte('.')
+	buf.WriteString(base64.RawURLEncoding.EncodeToString(sig))
+
+	return buf.String(), nil
+}
+
+// parsePrivateKey parses PEM encoded private key github generates for the app.
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid private key: no PEM data")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("invalid private key: not RSA")
+	}
+	return rsaKey, nil
+}
@@ -0,0 +1,83 @@
+package github
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEnterpriseURLs(t *testing.T) {
+	cases := []struct {
+		baseURL string
+		apiURL  string
+		htmlURL string
+	}{
+		{"https://ghe.example.com", "https://ghe.example.com/api/v3/", "https://ghe.example.com/"},
+		{"https://ghe.example.com/", "https://ghe.example.com/api/v3/", "https://ghe.example.com/"},
+		{"https://ghe.example.com/api/v3", "https://ghe.example.com/api/v3/", "https://ghe.example.com/"},
+		{"https://ghe.example.com/api/v3/", "https://ghe.example.com/api/v3/", "https://ghe.example.com/"},
+	}
+	for _, c := range cases {
+		htmlURL, apiURL, _, err := enterpriseURLs(c.baseURL, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if apiURL != c.apiURL || htmlURL.String() != c.htmlURL {
+			t.Errorf("enterpriseURLs(%q) = %s %s, want %s %s", c.baseURL, htmlURL, apiURL, c.htmlURL, c.apiURL)
+		}
+	}
+}
+
+func TestInstallationToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	var tokenRequests int
+	var authorization string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/app/installations/42/access_tokens":
+			tokenRequests++
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"token":"installation-token","expires_at":"2099-01-01T00:00:00Z"}`)
+		case "/api/v3/users/golang":
+			authorization = r.Header.Get("Authorization")
+			fmt.Fprint(w, `{"login":"golang"}`)
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	for _, baseURL := range []string{srv.URL, srv.URL + "/api/v3", srv.URL + "/api/v3/"} {
+		tokenRequests = 0
+		authorization = ""
+		c, err := Connect(&ConnectOption{
+			BaseURL:        baseURL,
+			AppID:          1,
+			InstallationID: 42,
+			AppPrivateKey:  privateKey,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := c.GetOwner(context.Background(), "golang"); err != nil {
+				t.Fatalf("%s: %s", baseURL, err)
+			}
+		}
+		if tokenRequests != 1 || authorization != "token installation-token" {
+			t.Errorf("%s: %d token requests, authorization %q", baseURL, tokenRequests, authorization)
+		}
+	}
+}
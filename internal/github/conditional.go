@@ -0,0 +1,104 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/kudohamu/watchcat/internal/lmdb"
+)
+
+const bktETag = "etag"
+
+type conditionalKey struct{}
+
+// validator is the cache validator github returned for a response.
+type validator struct {
+	ETag         string `json:"etag"`
+	LastModified string `json:"lastModified"`
+}
+
+// conditional makes the request carrying it conditional.
+// validator of the response is kept until the caller commits it after handling the response,
+// so that the response is requested again if the caller fails.
+type conditional struct {
+	scope string
+	key   string
+	next  validator
+}
+
+// conditionalTransport sends If-None-Match and If-Modified-Since
+// for requests with conditional in their context.
+type conditionalTransport struct {
+	base  http.RoundTripper
+	store lmdb.Store
+}
+
+// withConditional returns context making requests with it conditional.
+// scope separates validators of callers requesting the same url.
+func withConditional(ctx context.Context, scope string) (context.Context, *conditional) {
+	c := &conditional{scope: scope}
+	return context.WithValue(ctx, conditionalKey{}, c), c
+}
+
+// RoundTrip sends request with stored validator.
+func (t *conditionalTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c, ok := req.Context().Value(conditionalKey{}).(*conditional)
+	if !ok || t.store == nil || req.Method != http.MethodGet {
+		return t.base.RoundTrip(req)
+	}
+
+	c.key = c.scope + " " + req.URL.String()
+	prev, err := readValidator(t.store, c.key)
+	if err == nil && prev != nil {
+		// RoundTripper must not modify the given request.
+		r := req.WithContext(req.Context())
+		r.Header = make(http.Header, len(req.Header))
+		for k, v := range req.Header {
+			r.Header[k] = v
+		}
+		if prev.ETag != "" {
+			r.Header.Set("If-None-Match", prev.ETag)
+		}
+		if prev.LastModified != "" {
+			r.Header.Set("If-Modified-Since", prev.LastModified)
+		}
+		req = r
+	}
+
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusOK {
+		c.next = validator{
+			ETag:         res.Header.Get("ETag"),
+			LastModified: res.Header.Get("Last-Modified"),
+		}
+	}
+	return res, nil
+}
+
+// commit stores validator of the response to make the next request conditional.
+func (c *conditional) commit(s lmdb.Store) error {
+	if s == nil || c.key == "" || (c.next.ETag == "" && c.next.LastModified == "") {
+		return nil
+	}
+	data, err := json.Marshal(c.next)
+	if err != nil {
+		return err
+	}
+	return s.Put(bktETag, c.key, data)
+}
+
+func readValidator(s lmdb.Store, key string) (*validator, error) {
+	data, err := s.Get(bktETag, key)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var v validator
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
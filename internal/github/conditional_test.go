@@ -0,0 +1,132 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/kudohamu/watchcat/internal/lmdb"
+)
+
+func TestConditionalTransport(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+		w.Header().Set("ETag", `"next"`)
+		w.Header().Set("Last-Modified", "Wed, 02 Jan 2019 00:00:00 GMT")
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		name         string
+		method       string
+		conditional  bool
+		scope        string
+		ifNoneMatch  string
+		ifModified   string
+		committedKey string
+	}{
+		{"no validator", http.MethodGet, true, "new", "", "", "new " + srv.URL},
+		{"stored validator", http.MethodGet, true, "commit", `"prev"`, "Tue, 01 Jan 2019 00:00:00 GMT", "commit " + srv.URL},
+		{"other scope", http.MethodGet, true, "issue", "", "", "issue " + srv.URL},
+		{"not conditional", http.MethodGet, false, "commit", "", "", ""},
+		{"not get", http.MethodPost, true, "commit", "", "", ""},
+	}
+	for _, c := range cases {
+		store := lmdb.NewMemoryStore()
+		store.Put(bktETag, "commit "+srv.URL, []byte(`{"etag":"\"prev\"","lastModified":"Tue, 01 Jan 2019 00:00:00 GMT"}`))
+		transport := &conditionalTransport{base: http.DefaultTransport, store: store}
+
+		ctx, cond := withConditional(context.Background(), c.scope)
+		if !c.conditional {
+			ctx = context.Background()
+		}
+		req, err := http.NewRequest(c.method, srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := transport.RoundTrip(req.WithContext(ctx))
+		if err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		res.Body.Close()
+
+		if got.Get("If-None-Match") != c.ifNoneMatch || got.Get("If-Modified-Since") != c.ifModified {
+			t.Errorf("%s: unexpected validator: %q %q", c.name, got.Get("If-None-Match"), got.Get("If-Modified-Since"))
+		}
+		if req.Header.Get("If-None-Match") != "" {
+			t.Errorf("%s: request is modified", c.name)
+		}
+
+		if err := cond.commit(store); err != nil {
+			t.Fatal(err)
+		}
+		if c.committedKey == "" {
+			continue
+		}
+		v, err := readValidator(store, c.committedKey)
+		if err != nil || v == nil || v.ETag != `"next"` || v.LastModified != "Wed, 02 Jan 2019 00:00:00 GMT" {
+			t.Errorf("%s: validator is not committed: %+v %v", c.name, v, err)
+		}
+	}
+}
+
+func TestCommitsUntilConditional(t *testing.T) {
+	var conditionalPages []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		if r.Header.Get("If-None-Match") != "" {
+			conditionalPages = append(conditionalPages, page)
+		}
+		if page == "2" {
+			fmt.Fprint(w, `[{"sha":"2"},{"sha":"1"}]`)
+			return
+		}
+		etag := `"page1"`
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Link", fmt.Sprintf(`<%s/repos/o/n/commits?page=2>; rel="next"`, "http://"+r.Host))
+		fmt.Fprint(w, `[{"sha":"4"},{"sha":"3"}]`)
+	}))
+	defer srv.Close()
+
+	store := lmdb.NewMemoryStore()
+	c, err := Connect(&ConnectOption{Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.client.BaseURL, _ = url.Parse(srv.URL + "/")
+	seen := func(commit *github.RepositoryCommit) bool {
+		return commit.GetSHA() == "1"
+	}
+
+	// the response is requested again until it is committed.
+	for i := 0; i < 2; i++ {
+		commits, _, err := c.CommitsUntil(context.Background(), "o", "n", "", 10, seen)
+		if err != nil || len(commits) != 3 {
+			t.Fatalf("unexpected commits: %d %v", len(commits), err)
+		}
+	}
+	_, commit, err := c.CommitsUntil(context.Background(), "o", "n", "", 10, seen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := c.CommitsUntil(context.Background(), "o", "n", "", 10, seen); err != ErrNotModified {
+		t.Errorf("got %v, want ErrNotModified", err)
+	}
+	// only the first page is requested conditionally.
+	if len(conditionalPages) != 1 || conditionalPages[0] != "" {
+		t.Errorf("conditional pages: %q", conditionalPages)
+	}
+}
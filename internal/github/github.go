@@ -3,153 +3,522 @@ package github
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/google/go-github/github"
+	"github.com/kudohamu/watchcat/internal/lmdb"
 	"golang.org/x/oauth2"
 )
 
-var client *github.Client
+// DefaultURL is the url of github.com.
+const DefaultURL = "https://github.com/"
+
+const defaultAPIURL = "https://api.github.com/"
+
+// Client is the client of a github host.
+type Client struct {
+	client  *github.Client
+	htmlURL *url.URL
+	// store persists validators of conditional requests.
+	store   lmdb.Store
+	limiter *limiter
+}
 
 // ErrNotFound is not found error.
 var ErrNotFound = errors.New("not found")
 
+// ErrNotModified is returned when nothing has changed since the last request.
+var ErrNotModified = errors.New("not modified")
+
+// issuesPerPage is page size for listing issues.
+// issues and pull requests are listed together, so it is not bounded by the limit.
+const issuesPerPage = 20
+
+// maxPerPage is the largest page size github accepts.
+const maxPerPage = 100
+
 // ConnectOption specifies optional parameter to connect github.
 type ConnectOption struct {
 	AccessToken string
+	// AppID, InstallationID and AppPrivateKey authenticate as installation of github app, instead of AccessToken.
+	AppID          int64
+	InstallationID int64
+	// AppPrivateKey is PEM encoded private key of the app.
+	AppPrivateKey []byte
+	// BaseURL is the url of github enterprise server, like https://github.example.com/.
+	// api url is derived from it unless it ends with /api/v3/. default is github.com.
+	BaseURL string
+	// UploadURL is the upload url of github enterprise server. default is derived from BaseURL.
+	UploadURL string
+	// Store persists ETags to make requests conditional.
+	Store lmdb.Store
 }
 
 // Connect creates github client.
-func Connect(op *ConnectOption) {
-	var tc *http.Client
-	if op != nil && op.AccessToken != "" {
-		ts := oauth2.StaticTokenSource(
+func Connect(op *ConnectOption) (*Client, error) {
+	if op == nil {
+		op = &ConnectOption{}
+	}
+
+	enterprise := op.BaseURL != "" && !IsDefaultURL(op.BaseURL)
+	htmlURL, _ := url.Parse(DefaultURL)
+	apiURL := defaultAPIURL
+	var uploadURL string
+	if enterprise {
+		var err error
+		htmlURL, apiURL, uploadURL, err = enterpriseURLs(op.BaseURL, op.UploadURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var transport http.RoundTripper = http.DefaultTransport
+	if op.Store != nil {
+		transport = &conditionalTransport{
+			base:  transport,
+			store: op.Store,
+		}
+	}
+
+	var ts oauth2.TokenSource
+	if op.AppID != 0 {
+		var err error
+		ts, err = newInstallationTokenSource(apiURL, op.AppID, op.InstallationID, op.AppPrivateKey)
+		if err != nil {
+			return nil, err
+		}
+	} else if op.AccessToken != "" {
+		ts = oauth2.StaticTokenSource(
 			&oauth2.Token{AccessToken: op.AccessToken},
 		)
-		tc = oauth2.NewClient(context.Background(), ts)
 	}
+	if ts != nil {
+		transport = &oauth2.Transport{
+			Source: ts,
+			Base:   transport,
+		}
+	}
+	hc := &http.Client{Transport: transport}
 
-	client = github.NewClient(tc)
+	c := &Client{
+		htmlURL: htmlURL,
+		store:   op.Store,
+		limiter: &limiter{},
+	}
+	if !enterprise {
+		c.client = github.NewClient(hc)
+		return c, nil
+	}
+
+	var err error
+	c.client, err = github.NewEnterpriseClient(apiURL, uploadURL, hc)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
-// Disconnect releases github client.
-func Disconnect() {
-	client = nil
+// IsDefaultURL reports whether u points to github.com.
+func IsDefaultURL(u string) bool {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	return pu.Host == "github.com" || pu.Host == "api.github.com"
 }
 
-// GetOwner gets owner info.
-func GetOwner(ctx context.Context, name string) (*github.User, error) {
-	owner, res, err := client.Users.Get(ctx, name)
-	if res != nil && res.StatusCode == 404 {
-		return nil, ErrNotFound
+// Host returns host name of u, which is used to tell github hosts apart.
+func Host(u string) string {
+	if u == "" || IsDefaultURL(u) {
+		return "github.com"
 	}
+	pu, err := url.Parse(u)
 	if err != nil {
-		return nil, err
+		return u
 	}
-	return owner, nil
+	return pu.Host
 }
 
-// LatestRelease fetches latest release of specified repository.
-func LatestRelease(ctx context.Context, owner string, name string) (*github.RepositoryRelease, error) {
-	release, res, err := client.Repositories.GetLatestRelease(ctx, owner, name)
-	if res != nil && res.StatusCode == 404 {
-		return nil, ErrNotFound
-	}
+// Host returns host name of github the client connects to.
+func (c *Client) Host() string {
+	return c.htmlURL.Host
+}
+
+// RepoURL returns url of repository page.
+func (c *Client) RepoURL(owner string, name string) string {
+	return c.htmlURL.ResolveReference(&url.URL{Path: fmt.Sprintf("%s/%s", owner, name)}).String()
+}
+
+// enterpriseURLs derives urls of github enterprise server from its url.
+func enterpriseURLs(baseURL string, uploadURL string) (htmlURL *url.URL, apiURL string, upURL string, err error) {
+	u, err := url.Parse(baseURL)
 	if err != nil {
-		return nil, err
+		return nil, "", "", err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, "", "", fmt.Errorf("invalid github url: %s", baseURL)
+	}
+
+	htmlURL = &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}
+	// api url ends with slash, so that paths are joined to it.
+	apiURL = htmlURL.ResolveReference(&url.URL{Path: "api/v3/"}).String()
+	if strings.HasSuffix(strings.TrimSuffix(u.Path, "/"), "/api/v3") {
+		apiURL = strings.TrimSuffix(baseURL, "/") + "/"
 	}
-	return release, nil
+	upURL = uploadURL
+	if upURL == "" {
+		upURL = htmlURL.ResolveReference(&url.URL{Path: "api/uploads/"}).String()
+	}
+	return htmlURL, apiURL, upURL, nil
 }
 
-// LatestCommit fetches latest tag of specified repository.
-func LatestCommit(ctx context.Context, owner string, name string) (*github.RepositoryCommit, error) {
-	commits, res, err := client.Repositories.ListCommits(ctx, owner, name, &github.CommitsListOptions{
-		ListOptions: github.ListOptions{
-			Page:    0,
-			PerPage: 1,
-		},
+// GetOwner gets owner info.
+func (c *Client) GetOwner(ctx context.Context, name string) (*github.User, error) {
+	var owner *github.User
+	err := c.call(ctx, func() (res *github.Response, err error) {
+		owner, res, err = c.client.Users.Get(ctx, name)
+		return res, err
 	})
-	if res != nil && res.StatusCode == 404 || len(commits) == 0 {
-		return nil, ErrNotFound
-	}
 	if err != nil {
 		return nil, err
 	}
-	return commits[0], nil
+	return owner, nil
 }
 
-// LatestIssue fetches latest issue of specified repository.
-func LatestIssue(ctx context.Context, owner string, name string) (*github.Issue, error) {
-	page := 0
+// ReleasesUntil fetches releases of specified repository from the latest one, until seen returns true.
+// releases which match returns false for are skipped.
+// at most limit releases are returned, newest first.
+// ErrNotModified is returned if the first page is not changed since the last call.
+// commit makes the next call conditional. call it only after found items are handled.
+func (c *Client) ReleasesUntil(ctx context.Context, owner string, name string, limit int, match func(*github.RepositoryRelease) bool, seen func(*github.RepositoryRelease) bool) (found []*github.RepositoryRelease, commit func() error, err error) {
+	pageCtx, cond := withConditional(ctx, "release")
+	opt := &github.ListOptions{PerPage: perPage(limit)}
 
 	for {
-		issues, res, err := client.Issues.ListByRepo(ctx, owner, name, &github.IssueListByRepoOptions{
-			State: "all",
-			ListOptions: github.ListOptions{
-				Page:    page,
-				PerPage: 20,
-			},
+		var releases []*github.RepositoryRelease
+		var res *github.Response
+		err := c.call(pageCtx, func() (*github.Response, error) {
+			var err error
+			releases, res, err = c.client.Repositories.ListReleases(pageCtx, owner, name, opt)
+			return res, err
 		})
-		if res != nil && res.StatusCode == 404 || len(issues) == 0 {
-			return nil, ErrNotFound
+		if err != nil {
+			return nil, nil, err
 		}
+		// only the first page is requested conditionally.
+		pageCtx = ctx
+
+		for _, release := range releases {
+			if seen(release) {
+				return found, c.committer(cond), nil
+			}
+			if !match(release) {
+				continue
+			}
+			found = append(found, release)
+			if len(found) >= limit {
+				return found, c.committer(cond), nil
+			}
+		}
+
+		if res.NextPage == 0 {
+			return found, c.committer(cond), nil
+		}
+		opt.Page = res.NextPage
+	}
+}
+
+// CommitsUntil fetches commits of branch of specified repository from the latest one, until seen returns true.
+// the default branch is used if branch is empty.
+// at most limit commits are returned, newest first.
+// ErrNotModified is returned if the first page is not changed since the last call.
+// commit makes the next call conditional. call it only after found items are handled.
+func (c *Client) CommitsUntil(ctx context.Context, owner string, name string, branch string, limit int, seen func(*github.RepositoryCommit) bool) (found []*github.RepositoryCommit, commit func() error, err error) {
+	pageCtx, cond := withConditional(ctx, "commit")
+	opt := &github.CommitsListOptions{
+		SHA:         branch,
+		ListOptions: github.ListOptions{PerPage: perPage(limit)},
+	}
+
+	for {
+		var commits []*github.RepositoryCommit
+		var res *github.Response
+		err := c.call(pageCtx, func() (*github.Response, error) {
+			var err error
+			commits, res, err = c.client.Repositories.ListCommits(pageCtx, owner, name, opt)
+			return res, err
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		// only the first page is requested conditionally.
+		pageCtx = ctx
+
+		for _, commit := range commits {
+			if seen(commit) {
+				return found, c.committer(cond), nil
+			}
+			found = append(found, commit)
+			if len(found) >= limit {
+				return found, c.committer(cond), nil
+			}
+		}
+
+		if res.NextPage == 0 {
+			return found, c.committer(cond), nil
+		}
+		opt.Page = res.NextPage
+	}
+}
+
+// CommitFiles gets names of files changed by the commit.
+func (c *Client) CommitFiles(ctx context.Context, owner string, name string, sha string) ([]string, error) {
+	var commit *github.RepositoryCommit
+	err := c.call(ctx, func() (res *github.Response, err error) {
+		commit, res, err = c.client.Repositories.GetCommit(ctx, owner, name, sha)
+		return res, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(commit.Files))
+	for _, f := range commit.Files {
+		files = append(files, f.GetFilename())
+	}
+	return files, nil
+}
+
+// Branches lists names of all branches of specified repository.
+func (c *Client) Branches(ctx context.Context, owner string, name string) ([]string, error) {
+	opt := &github.ListOptions{PerPage: maxPerPage}
+	var names []string
+	for {
+		var branches []*github.Branch
+		var res *github.Response
+		err := c.call(ctx, func() (*github.Response, error) {
+			var err error
+			branches, res, err = c.client.Repositories.ListBranches(ctx, owner, name, opt)
+			return res, err
+		})
 		if err != nil {
 			return nil, err
 		}
+		for _, b := range branches {
+			names = append(names, b.GetName())
+		}
+
+		if res.NextPage == 0 {
+			return names, nil
+		}
+		opt.Page = res.NextPage
+	}
+}
+
+// IssueQuery narrows issues and pull requests listed by github.
+type IssueQuery struct {
+	// Labels lists only issues having all of them.
+	Labels  []string
+	Creator string
+}
+
+// IssuesUntil fetches issues of specified repository from the latest one, until seen returns true.
+// at most limit issues are returned, newest first.
+// ErrNotModified is returned if the first page is not changed since the last call.
+// commit makes the next call conditional. call it only after found items are handled.
+func (c *Client) IssuesUntil(ctx context.Context, owner string, name string, query *IssueQuery, limit int, seen func(*github.Issue) bool) ([]*github.Issue, func() error, error) {
+	return c.listIssuesUntil(ctx, "issue", owner, name, query, limit, func(issue *github.Issue) bool {
+		// if PullRequestLinks is not nil, that's pull request.
+		return issue.PullRequestLinks == nil
+	}, seen)
+}
+
+// PRIssuesUntil fetches pull requests of specified repository from the latest one, until seen returns true.
+// at most limit pull requests are returned, newest first.
+// ErrNotModified is returned if the first page is not changed since the last call.
+// commit makes the next call conditional. call it only after found items are handled.
+// PR is every pull request is an issue. see https://godoc.org/github.com/google/go-github/github/issues.go?s=780:2350#L16
+func (c *Client) PRIssuesUntil(ctx context.Context, owner string, name string, query *IssueQuery, limit int, seen func(*github.Issue) bool) ([]*github.Issue, func() error, error) {
+	return c.listIssuesUntil(ctx, "pr", owner, name, query, limit, func(issue *github.Issue) bool {
+		// if PullRequestLinks is not nil, this is a pull request.
+		return issue.PullRequestLinks != nil
+	}, seen)
+}
+
+func (c *Client) listIssuesUntil(ctx context.Context, scope string, owner string, name string, query *IssueQuery, limit int, match func(*github.Issue) bool, seen func(*github.Issue) bool) (found []*github.Issue, commit func() error, err error) {
+	pageCtx, cond := withConditional(ctx, scope)
+	opt := &github.IssueListByRepoOptions{
+		State: "all",
+		ListOptions: github.ListOptions{
+			PerPage: issuesPerPage,
+		},
+	}
+	if query != nil {
+		opt.Labels = query.Labels
+		opt.Creator = query.Creator
+	}
+
+	for {
+		var issues []*github.Issue
+		var res *github.Response
+		err := c.call(pageCtx, func() (*github.Response, error) {
+			var err error
+			issues, res, err = c.client.Issues.ListByRepo(pageCtx, owner, name, opt)
+			return res, err
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		// only the first page is requested conditionally.
+		pageCtx = ctx
 
 		for _, issue := range issues {
-			// if PullRequestLinks is not nil, that's pull request.
-			if issue.PullRequestLinks == nil {
-				return issue, nil
+			if seen(issue) {
+				return found, c.committer(cond), nil
+			}
+			if !match(issue) {
+				continue
+			}
+			found = append(found, issue)
+			if len(found) >= limit {
+				return found, c.committer(cond), nil
 			}
 		}
 
-		page++
+		if res.NextPage == 0 {
+			return found, c.committer(cond), nil
+		}
+		opt.Page = res.NextPage
 	}
 }
 
-// LatestPRIssue fetches latest pr of specified repository.
-// PR is every pull request is an issue. see https://godoc.org/github.com/google/go-github/github/issues.go?s=780:2350#L16
-func LatestPRIssue(ctx context.Context, owner string, name string) (*github.Issue, error) {
-	page := 0
+// PullRequest is the pull request with fields go-github doesn't support yet.
+type PullRequest struct {
+	github.PullRequest
+	Draft *bool `json:"draft,omitempty"`
+}
+
+// GetDraft returns whether the pull request is draft.
+func (pr *PullRequest) GetDraft() bool {
+	return pr.Draft != nil && *pr.Draft
+}
+
+// PullRequestsUpdatedUntil fetches pull requests of any state of specified repository from the last updated one, until seen returns true.
+// at most limit pull requests are returned, last updated first.
+// ErrNotModified is returned if the first page is not changed since the last call.
+// commit makes the next call conditional. call it only after found items are handled.
+func (c *Client) PullRequestsUpdatedUntil(ctx context.Context, owner string, name string, limit int, seen func(*PullRequest) bool) (found []*PullRequest, commit func() error, err error) {
+	pageCtx, cond := withConditional(ctx, "pr_state")
+	page := 1
 
 	for {
-		issues, res, err := client.Issues.ListByRepo(ctx, owner, name, &github.IssueListByRepoOptions{
-			State: "all",
-			ListOptions: github.ListOptions{
-				Page:    page,
-				PerPage: 20,
-			},
+		var prs []*PullRequest
+		var res *github.Response
+		err := c.call(pageCtx, func() (*github.Response, error) {
+			// go-github is not used to decode draft of pull requests.
+			u := fmt.Sprintf("repos/%s/%s/pulls?state=all&sort=updated&direction=desc&per_page=%d&page=%d", owner, name, perPage(limit), page)
+			req, err := c.client.NewRequest(http.MethodGet, u, nil)
+			if err != nil {
+				return nil, err
+			}
+			prs = nil
+			res, err = c.client.Do(pageCtx, req, &prs)
+			return res, err
 		})
-		if res != nil && res.StatusCode == 404 || len(issues) == 0 {
-			return nil, ErrNotFound
+		if err != nil {
+			return nil, nil, err
 		}
+		// only the first page is requested conditionally.
+		pageCtx = ctx
+
+		for _, pr := range prs {
+			if seen(pr) {
+				return found, c.committer(cond), nil
+			}
+			found = append(found, pr)
+			if len(found) >= limit {
+				return found, c.committer(cond), nil
+			}
+		}
+
+		if res.NextPage == 0 {
+			return found, c.committer(cond), nil
+		}
+		page = res.NextPage
+	}
+}
+
+// TagsUntil fetches tags of specified repository in the order github returns, until seen returns true.
+// tags which match returns false for are skipped.
+// at most limit tags are returned.
+// ErrNotModified is returned if the first page is not changed since the last call.
+// commit makes the next call conditional. call it only after found items are handled.
+func (c *Client) TagsUntil(ctx context.Context, owner string, name string, limit int, match func(*github.RepositoryTag) bool, seen func(*github.RepositoryTag) bool) (found []*github.RepositoryTag, commit func() error, err error) {
+	pageCtx, cond := withConditional(ctx, "tag")
+	opt := &github.ListOptions{PerPage: perPage(limit)}
+
+	for {
+		var tags []*github.RepositoryTag
+		var res *github.Response
+		err := c.call(pageCtx, func() (*github.Response, error) {
+			var err error
+			tags, res, err = c.client.Repositories.ListTags(pageCtx, owner, name, opt)
+			return res, err
+		})
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
+		// only the first page is requested conditionally.
+		pageCtx = ctx
 
-		for _, issue := range issues {
-			// if PullRequestLinks is not nil, this is a pull request.
-			if issue.PullRequestLinks != nil {
-				return issue, nil
+		for _, tag := range tags {
+			if seen(tag) {
+				return found, c.committer(cond), nil
+			}
+			if !match(tag) {
+				continue
+			}
+			found = append(found, tag)
+			if len(found) >= limit {
+				return found, c.committer(cond), nil
 			}
 		}
 
-		page++
+		if res.NextPage == 0 {
+			return found, c.committer(cond), nil
+		}
+		opt.Page = res.NextPage
 	}
 }
 
-// LatestTag fetches latest tag of specified repository.
-func LatestTag(ctx context.Context, owner string, name string) (*github.RepositoryTag, error) {
-	tags, res, err := client.Repositories.ListTags(ctx, owner, name, &github.ListOptions{
-		Page:    0,
-		PerPage: 1,
-	})
-	if res != nil && res.StatusCode == 404 || len(tags) == 0 {
-		return nil, ErrNotFound
+// check converts status code of response to error.
+func check(res *github.Response, err error) error {
+	if res != nil {
+		switch res.StatusCode {
+		case http.StatusNotFound:
+			return ErrNotFound
+		case http.StatusNotModified:
+			return ErrNotModified
+		}
 	}
-	if err != nil {
-		return nil, err
+	return err
+}
+
+// committer returns commit function which stores validator of the conditional request.
+// listing functions return it instead of storing the validator themselves,
+// so that items are listed again unless the caller has handled them.
+func (c *Client) committer(cond *conditional) func() error {
+	return func() error {
+		return cond.commit(c.store)
+	}
+}
+
+func perPage(limit int) int {
+	if limit <= 0 || limit > maxPerPage {
+		return maxPerPage
 	}
-	return tags[0], nil
+	return limit
 }
@@ -0,0 +1,109 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// maxAbuseRetries is how many times a request is retried on abuse rate limit.
+const maxAbuseRetries = 3
+
+// defaultRetryAfter is used when abuse rate limit response has no Retry-After.
+const defaultRetryAfter = time.Minute
+
+// RateLimitError is returned while the rate limit of github api is exceeded.
+type RateLimitError struct {
+	Reset time.Time
+	// First reports whether this is the first error since the rate limit was exceeded.
+	First bool
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited until %s", e.Reset.Local().Format("15:04"))
+}
+
+// limiter tracks the rate limit shared by all requests.
+type limiter struct {
+	mu       sync.Mutex
+	reset    time.Time
+	reported time.Time
+}
+
+// RateLimitedUntil returns the time rate limit of the host is reset, if it is exceeded now.
+func (c *Client) RateLimitedUntil() (time.Time, bool) {
+	c.limiter.mu.Lock()
+	defer c.limiter.mu.Unlock()
+
+	return c.limiter.reset, time.Now().Before(c.limiter.reset)
+}
+
+// observe tracks remaining quota from response.
+func (l *limiter) observe(res *github.Response) {
+	// github enterprise may have no rate limit.
+	if res == nil || res.Rate.Limit == 0 || res.Rate.Remaining > 0 {
+		return
+	}
+	l.exceed(res.Rate.Reset.Time)
+}
+
+func (l *limiter) exceed(reset time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if reset.After(l.reset) {
+		l.reset = reset
+	}
+}
+
+// check returns error if rate limit is exceeded now.
+func (l *limiter) check() *RateLimitError {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !time.Now().Before(l.reset) {
+		return nil
+	}
+	first := !l.reported.Equal(l.reset)
+	l.reported = l.reset
+	return &RateLimitError{Reset: l.reset, First: first}
+}
+
+// call calls github api unless rate limit is exceeded,
+// retrying it after waiting if github responds abuse rate limit.
+func (c *Client) call(ctx context.Context, fn func() (*github.Response, error)) error {
+	for retry := 0; ; retry++ {
+		if err := c.limiter.check(); err != nil {
+			return err
+		}
+
+		res, err := fn()
+		c.limiter.observe(res)
+
+		switch e := err.(type) {
+		case *github.RateLimitError:
+			c.limiter.exceed(e.Rate.Reset.Time)
+			if err := c.limiter.check(); err != nil {
+				return err
+			}
+		case *github.AbuseRateLimitError:
+			if retry >= maxAbuseRetries {
+				return err
+			}
+			wait := defaultRetryAfter
+			if e.RetryAfter != nil {
+				wait = *e.RetryAfter
+			}
+			select {
+			case <-time.After(wait):
+				continue
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return check(res, err)
+	}
+}
@@ -0,0 +1,112 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLimiterCheck(t *testing.T) {
+	now := time.Now()
+	l := &limiter{}
+	cases := []struct {
+		name    string
+		reset   time.Time
+		limited bool
+		first   bool
+	}{
+		{"not limited", time.Time{}, false, false},
+		{"limited", now.Add(time.Hour), true, true},
+		{"still limited", now.Add(time.Hour), true, false},
+		{"earlier reset is ignored", now.Add(time.Minute), true, false},
+		{"limited again", now.Add(2 * time.Hour), true, true},
+	}
+	for _, c := range cases {
+		l.exceed(c.reset)
+		err := l.check()
+		if (err != nil) != c.limited {
+			t.Fatalf("%s: unexpected error: %v", c.name, err)
+		}
+		if err != nil && err.First != c.first {
+			t.Errorf("%s: first = %v, want %v", c.name, err.First, c.first)
+		}
+	}
+
+	l = &limiter{reset: now.Add(-time.Second)}
+	if err := l.check(); err != nil {
+		t.Errorf("limited after reset: %v", err)
+	}
+}
+
+func TestClientCall(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Unix()
+	rateLimited := func(w http.ResponseWriter) {
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", fmt.Sprint(reset))
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message":"API rate limit exceeded for user ID 1."}`)
+	}
+	abused := func(retryAfter string) func(w http.ResponseWriter) {
+		return func(w http.ResponseWriter) {
+			w.Header().Set("Retry-After", retryAfter)
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message":"abuse","documentation_url":"https://developer.github.com/v3/#abuse-rate-limits"}`)
+		}
+	}
+	ok := func(w http.ResponseWriter) {
+		fmt.Fprint(w, `{"login":"golang"}`)
+	}
+
+	// abuse rate limit is waited until ctx is done.
+	timeout, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	cases := []struct {
+		name      string
+		ctx       context.Context
+		responses []func(w http.ResponseWriter)
+		requests  int
+		check     func(err error) bool
+	}{
+		{"ok", context.Background(), nil, 1, func(err error) bool { return err == nil }},
+		{"abuse is retried", context.Background(), []func(w http.ResponseWriter){abused("0"), abused("0")}, 3, func(err error) bool { return err == nil }},
+		{"abuse gives up", context.Background(), []func(w http.ResponseWriter){abused("0"), abused("0"), abused("0"), abused("0")}, maxAbuseRetries + 1, func(err error) bool { return err != nil }},
+		{"timed out while waiting", timeout, []func(w http.ResponseWriter){abused("60")}, 1, func(err error) bool { return err == context.DeadlineExceeded }},
+		{"rate limited", context.Background(), []func(w http.ResponseWriter){rateLimited}, 1, func(err error) bool {
+			e, ok := err.(*RateLimitError)
+			return ok && e.First && e.Reset.Unix() == reset
+		}},
+	}
+	for _, c := range cases {
+		var requests int
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if requests <= len(c.responses) {
+				c.responses[requests-1](w)
+				return
+			}
+			ok(w)
+		}))
+
+		client, err := Connect(&ConnectOption{BaseURL: srv.URL, AccessToken: "token"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = client.GetOwner(c.ctx, "golang")
+		if !c.check(err) || requests != c.requests {
+			t.Errorf("%s: %d requests, error %v", c.name, requests, err)
+		}
+
+		// requests are not sent until the rate limit is reset.
+		if _, limited := client.RateLimitedUntil(); limited {
+			_, err := client.GetOwner(context.Background(), "golang")
+			if e, ok := err.(*RateLimitError); !ok || e.First || requests != c.requests {
+				t.Errorf("%s: %d requests while rate limited, error %v", c.name, requests, err)
+			}
+		}
+		srv.Close()
+	}
+}
@@ -0,0 +1,120 @@
+package lmdb
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// lockTimeout is how long to wait for other processes releasing the file.
+const lockTimeout = 30 * time.Second
+
+var (
+	sharedMu sync.Mutex
+	// shared keeps files opened in this process by absolute path,
+	// so that stores of the same file share one connection instead of waiting for its lock.
+	shared = map[string]*sharedDB{}
+)
+
+type sharedDB struct {
+	db   *bolt.DB
+	refs int
+}
+
+// BoltStore is the Store backed by boltdb file.
+// the file is kept open and locked until Close, so it can't be shared with other processes.
+// stores of the same file in one process share the connection.
+type BoltStore struct {
+	path string
+	db   *bolt.DB
+
+	closeOnce sync.Once
+}
+
+// OpenBolt opens boltdb file at path, creating it if it does not exist.
+func OpenBolt(p string) (*BoltStore, error) {
+	if err := makeParentDir(p); err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	if s, ok := shared[abs]; ok {
+		s.refs++
+		return &BoltStore{path: abs, db: s.db}, nil
+	}
+
+	db, err := bolt.Open(abs, 0755, &bolt.Options{Timeout: lockTimeout})
+	if err == bolt.ErrTimeout {
+		return nil, fmt.Errorf("%s is locked by another process", abs)
+	}
+	if err != nil {
+		return nil, err
+	}
+	shared[abs] = &sharedDB{db: db, refs: 1}
+	return &BoltStore{path: abs, db: db}, nil
+}
+
+// Get gets value of key from bucket.
+func (s *BoltStore) Get(bucket string, key string) ([]byte, error) {
+	var value []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket([]byte(bucket))
+		if bkt == nil {
+			return nil
+		}
+
+		// value is only valid in the transaction.
+		if v := bkt.Get([]byte(key)); v != nil {
+			value = append([]byte{}, v...)
+		}
+		return nil
+	})
+	return value, err
+}
+
+// Put stores value of key into bucket.
+func (s *BoltStore) Put(bucket string, key string, value []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bkt, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return err
+		}
+		return bkt.Put([]byte(key), value)
+	})
+}
+
+// Delete deletes key from bucket.
+func (s *BoltStore) Delete(bucket string, key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket([]byte(bucket))
+		if bkt == nil {
+			return nil
+		}
+		return bkt.Delete([]byte(key))
+	})
+}
+
+// Close releases the file, which is closed when all stores of it in this process are closed.
+func (s *BoltStore) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		sharedMu.Lock()
+		defer sharedMu.Unlock()
+
+		shared[s.path].refs--
+		if shared[s.path].refs > 0 {
+			return
+		}
+		delete(shared, s.path)
+		err = s.db.Close()
+	})
+	return err
+}
@@ -0,0 +1,56 @@
+package lmdb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBoltStoreShared(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watchcat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	p := filepath.Join(dir, "watchcat.db")
+
+	a, err := OpenBolt(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the file opened in this process is shared instead of waiting for its lock.
+	b, err := OpenBolt(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Put("repo", "golang/go/release", []byte("go1.12")); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := b.Get("repo", "golang/go/release"); err != nil || string(v) != "go1.12" {
+		t.Errorf("got %q %v from shared store", v, err)
+	}
+
+	// the file is kept open until all stores of it are closed.
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := b.Get("repo", "golang/go/release"); err != nil || string(v) != "go1.12" {
+		t.Errorf("got %q %v after other store is closed", v, err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := OpenBolt(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if v, err := c.Get("repo", "golang/go/release"); err != nil || string(v) != "go1.12" {
+		t.Errorf("got %q %v after reopened", v, err)
+	}
+}
@@ -0,0 +1,102 @@
+package lmdb
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// FileStore is the Store persisting state into a flat json file.
+// the file is loaded only when opened, so it must not be shared between processes.
+// the whole file is rewritten on every change, so it suits small number of repositories.
+type FileStore struct {
+	mu      sync.RWMutex
+	path    string
+	buckets map[string]map[string]string
+}
+
+// OpenFile opens json file at path, creating it on the first write if it does not exist.
+func OpenFile(p string) (*FileStore, error) {
+	if err := makeParentDir(p); err != nil {
+		return nil, err
+	}
+
+	s := &FileStore{
+		path:    p,
+		buckets: map[string]map[string]string{},
+	}
+
+	data, err := ioutil.ReadFile(p)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return s, nil
+	}
+	if err := json.Unmarshal(data, &s.buckets); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get gets value of key from bucket.
+func (s *FileStore) Get(bucket string, key string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, ok := s.buckets[bucket][key]
+	if !ok {
+		return nil, nil
+	}
+	return []byte(v), nil
+}
+
+// Put stores value of key into bucket and writes the file.
+func (s *FileStore) Put(bucket string, key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bkt, ok := s.buckets[bucket]
+	if !ok {
+		bkt = map[string]string{}
+		s.buckets[bucket] = bkt
+	}
+	bkt[key] = string(value)
+	return s.flush()
+}
+
+// Delete deletes key from bucket and writes the file.
+func (s *FileStore) Delete(bucket string, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.buckets[bucket][key]; !ok {
+		return nil
+	}
+	delete(s.buckets[bucket], key)
+	return s.flush()
+}
+
+// Close does nothing because every change is already written.
+func (s *FileStore) Close() error {
+	return nil
+}
+
+// flush writes all state to a temporary file and replaces the file with it,
+// so the file is never left half written.
+func (s *FileStore) flush() error {
+	data, err := json.MarshalIndent(s.buckets, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
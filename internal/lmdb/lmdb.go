@@ -1,23 +1,21 @@
 package lmdb
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
-	"path"
 	"time"
-
-	"github.com/boltdb/bolt"
-	homedir "github.com/mitchellh/go-homedir"
 )
 
-var conn *bolt.DB
-
 // Repo is the LMDB store to store current repository state.
 type Repo struct {
-	Owner   string
-	Name    string
-	Target  string
+	// Host separates state of repositories of the same name on other github hosts. empty is github.com.
+	Host   string
+	Owner  string
+	Name   string
+	Target string
+	// Ref separates state of target per branch. empty is the default branch.
+	Ref     string
 	Current string
 }
 
@@ -28,113 +26,108 @@ type Owner struct {
 	CachedAt  time.Time `json:"cachedAt"`
 }
 
+// PullRequest is the LMDB store to store the last seen state of pull request.
+type PullRequest struct {
+	Host   string `json:"-"`
+	Owner  string `json:"-"`
+	Name   string `json:"-"`
+	Number int    `json:"-"`
+	// State is "open" or "closed", or empty if it has never been seen.
+	State string `json:"state"`
+	Draft bool   `json:"draft"`
+}
+
 const timeFormat = "2006-01-02 15:04:05 -0700"
 
-var bktOwer = []byte("owner")
-var bktRepo = []byte("repo")
+const bktOwer = "owner"
+const bktRepo = "repo"
+const bktPullRequest = "pull_request"
 
-// Connect connects to lmdb.
-func Connect() error {
-	var err error
-	hd, err := homedir.Dir()
+// Read reads stored current target information of repository.
+func (repo *Repo) Read(s Store) error {
+	key := repo.key()
+	value, err := s.Get(bktRepo, key)
 	if err != nil {
 		return err
 	}
-	dir := path.Join(hd, ".config", "watchcat")
+	repo.Current = string(value)
 
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
-	}
+	return nil
+}
 
-	p := path.Join(dir, "watchcat.db")
+// Write stores target information of repository.
+func (repo *Repo) Write(s Store) error {
+	key := repo.key()
+	return s.Put(bktRepo, key, []byte(repo.Current))
+}
+
+// key returns key of the state.
+// ":" never appears in branch names, so it separates branch from target.
+func (repo *Repo) key() string {
+	key := fmt.Sprintf("%s/%s/%s", repo.Owner, repo.Name, repo.Target)
+	if repo.Ref != "" {
+		key += ":" + repo.Ref
+	}
+	if repo.Host != "" {
+		key = repo.Host + "/" + key
+	}
+	return key
+}
 
-	conn, err = bolt.Open(p, 0755, nil)
+// Read reads cached avatar of owner.
+func (o *Owner) Read(s Store) error {
+	avatarURL, err := s.Get(bktOwer, fmt.Sprintf("%s/%s", o.Name, "avatar"))
 	if err != nil {
 		return err
 	}
+	o.AvatarURL = string(avatarURL)
 
-	// create buckets
-	return conn.Update(func(tx *bolt.Tx) error {
-		if _, err := tx.CreateBucketIfNotExists(bktRepo); err != nil {
-			return err
-		}
-		if _, err := tx.CreateBucketIfNotExists(bktOwer); err != nil {
-			return err
-		}
-
-		return nil
-	})
-}
-
-// Disconnect disconnects from lmdb.
-func Disconnect() error {
-	if err := conn.Close(); err != nil {
+	cachedAt, err := s.Get(bktOwer, fmt.Sprintf("%s/%s", o.Name, "cachedAt"))
+	if err != nil {
+		return err
+	}
+	if len(cachedAt) == 0 {
+		return errors.New("not found")
+	}
+	cAt, err := time.Parse(timeFormat, string(cachedAt))
+	if err != nil {
 		return err
 	}
-	conn = nil
+	o.CachedAt = cAt
 
 	return nil
 }
 
-// Read reads stored current target information of repository.
-func (repo *Repo) Read() error {
-	return conn.Update(func(tx *bolt.Tx) error {
-		bkt := tx.Bucket(bktRepo)
-
-		key := fmt.Sprintf("%s/%s/%s", repo.Owner, repo.Name, repo.Target)
-		value := bkt.Get([]byte(key))
-		repo.Current = string(value)
-
-		return nil
-	})
+// Write caches avatar of owner.
+func (o *Owner) Write(s Store) error {
+	if err := s.Put(bktOwer, fmt.Sprintf("%s/%s", o.Name, "avatar"), []byte(o.AvatarURL)); err != nil {
+		return err
+	}
+	return s.Put(bktOwer, fmt.Sprintf("%s/%s", o.Name, "cachedAt"), []byte(o.CachedAt.Format(timeFormat)))
 }
 
-// Write stores target information of repository.
-func (repo *Repo) Write() error {
-	return conn.Update(func(tx *bolt.Tx) error {
-		bkt := tx.Bucket(bktRepo)
-
-		key := fmt.Sprintf("%s/%s/%s", repo.Owner, repo.Name, repo.Target)
-		if err := bkt.Put([]byte(key), []byte(repo.Current)); err != nil {
-			return err
-		}
-
-		return nil
-	})
+// Read reads the last seen state of pull request.
+func (pr *PullRequest) Read(s Store) error {
+	value, err := s.Get(bktPullRequest, pr.key())
+	if err != nil || value == nil {
+		return err
+	}
+	return json.Unmarshal(value, pr)
 }
 
-func (o *Owner) Read() error {
-	return conn.Update(func(tx *bolt.Tx) error {
-		bkt := tx.Bucket(bktOwer)
-
-		avatarURL := bkt.Get([]byte(fmt.Sprintf("%s/%s", o.Name, "avatar")))
-		o.AvatarURL = string(avatarURL)
-
-		cachedAt := bkt.Get([]byte(fmt.Sprintf("%s/%s", o.Name, "cachedAt")))
-		if len(cachedAt) == 0 {
-			return errors.New("not found")
-		}
-		cAt, err := time.Parse(timeFormat, string(cachedAt))
-		if err != nil {
-			return err
-		}
-		o.CachedAt = cAt
-
-		return nil
-	})
+// Write stores state of pull request.
+func (pr *PullRequest) Write(s Store) error {
+	value, err := json.Marshal(pr)
+	if err != nil {
+		return err
+	}
+	return s.Put(bktPullRequest, pr.key(), value)
 }
 
-func (o *Owner) Write() error {
-	return conn.Update(func(tx *bolt.Tx) error {
-		bkt := tx.Bucket(bktOwer)
-
-		if err := bkt.Put([]byte(fmt.Sprintf("%s/%s", o.Name, "avatar")), []byte(o.AvatarURL)); err != nil {
-			return err
-		}
-		if err := bkt.Put([]byte(fmt.Sprintf("%s/%s", o.Name, "cachedAt")), []byte(o.CachedAt.Format(timeFormat))); err != nil {
-			return err
-		}
-
-		return nil
-	})
+func (pr *PullRequest) key() string {
+	key := fmt.Sprintf("%s/%s/%d", pr.Owner, pr.Name, pr.Number)
+	if pr.Host != "" {
+		key = pr.Host + "/" + key
+	}
+	return key
 }
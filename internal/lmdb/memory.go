@@ -0,0 +1,59 @@
+package lmdb
+
+import (
+	"sync"
+)
+
+// MemoryStore is the Store keeping state in memory only.
+// state is lost when process exits, so it is for tests and ephemeral runs.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	buckets map[string]map[string][]byte
+}
+
+// NewMemoryStore creates empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: map[string]map[string][]byte{},
+	}
+}
+
+// Get gets value of key from bucket.
+func (s *MemoryStore) Get(bucket string, key string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, ok := s.buckets[bucket][key]
+	if !ok {
+		return nil, nil
+	}
+	return append([]byte{}, v...), nil
+}
+
+// Put stores value of key into bucket.
+func (s *MemoryStore) Put(bucket string, key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bkt, ok := s.buckets[bucket]
+	if !ok {
+		bkt = map[string][]byte{}
+		s.buckets[bucket] = bkt
+	}
+	bkt[key] = append([]byte{}, value...)
+	return nil
+}
+
+// Delete deletes key from bucket.
+func (s *MemoryStore) Delete(bucket string, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.buckets[bucket], key)
+	return nil
+}
+
+// Close does nothing.
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,35 @@
+package lmdb
+
+// namespaceStore prefixes every key with namespace,
+// so that watchers sharing one store never touch other's state.
+type namespaceStore struct {
+	Store
+	prefix string
+}
+
+// WithNamespace wraps s to isolate keys under namespace.
+// s is returned as is if namespace is empty.
+func WithNamespace(s Store, namespace string) Store {
+	if namespace == "" {
+		return s
+	}
+	return &namespaceStore{
+		Store:  s,
+		prefix: namespace + "/",
+	}
+}
+
+// Get gets value of namespaced key from bucket.
+func (s *namespaceStore) Get(bucket string, key string) ([]byte, error) {
+	return s.Store.Get(bucket, s.prefix+key)
+}
+
+// Put stores value of namespaced key into bucket.
+func (s *namespaceStore) Put(bucket string, key string, value []byte) error {
+	return s.Store.Put(bucket, s.prefix+key, value)
+}
+
+// Delete deletes namespaced key from bucket.
+func (s *namespaceStore) Delete(bucket string, key string) error {
+	return s.Store.Delete(bucket, s.prefix+key)
+}
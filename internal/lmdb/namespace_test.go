@@ -0,0 +1,46 @@
+package lmdb
+
+import "testing"
+
+func TestWithNamespace(t *testing.T) {
+	s := NewMemoryStore()
+	if WithNamespace(s, "") != Store(s) {
+		t.Error("store is wrapped without namespace")
+	}
+
+	a := WithNamespace(s, "team-a")
+	b := WithNamespace(s, "team-b")
+	if err := a.Put("repo", "golang/go/release", []byte("go1.12")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Put("repo", "golang/go/release", []byte("go1.11")); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		store Store
+		key   string
+		want  string
+	}{
+		{a, "golang/go/release", "go1.12"},
+		{b, "golang/go/release", "go1.11"},
+		{s, "team-a/golang/go/release", "go1.12"},
+		{s, "golang/go/release", ""},
+	}
+	for _, c := range cases {
+		v, err := c.store.Get("repo", c.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(v) != c.want {
+			t.Errorf("%s = %q, want %q", c.key, v, c.want)
+		}
+	}
+
+	if err := a.Delete("repo", "golang/go/release"); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := b.Get("repo", "golang/go/release"); string(v) != "go1.11" {
+		t.Errorf("key of other namespace is deleted: %q", v)
+	}
+}
@@ -0,0 +1,30 @@
+package lmdb
+
+import (
+	"os"
+	"path/filepath"
+
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+// Store is the key-value storage to persist watching state.
+type Store interface {
+	// Get returns nil if key does not exist.
+	Get(bucket string, key string) ([]byte, error)
+	Put(bucket string, key string, value []byte) error
+	Delete(bucket string, key string) error
+	Close() error
+}
+
+// DefaultPath returns path of the file named filename under watchcat's config directory.
+func DefaultPath(filename string) (string, error) {
+	hd, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(hd, ".config", "watchcat", filename), nil
+}
+
+func makeParentDir(p string) error {
+	return os.MkdirAll(filepath.Dir(p), 0755)
+}
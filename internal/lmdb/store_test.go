@@ -0,0 +1,75 @@
+package lmdb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStores(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watchcat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		name string
+		open func() (Store, error)
+	}{
+		{"memory", func() (Store, error) { return NewMemoryStore(), nil }},
+		{"file", func() (Store, error) { return OpenFile(filepath.Join(dir, "state", "watchcat.json")) }},
+		{"bolt", func() (Store, error) { return OpenBolt(filepath.Join(dir, "state", "watchcat.db")) }},
+	}
+	for _, c := range cases {
+		s, err := c.open()
+		if err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+
+		if v, err := s.Get("repo", "golang/go/release"); err != nil || v != nil {
+			t.Errorf("%s: got %q %v from missing bucket", c.name, v, err)
+		}
+		if err := s.Delete("repo", "golang/go/release"); err != nil {
+			t.Errorf("%s: delete from missing bucket: %s", c.name, err)
+		}
+		if err := s.Put("repo", "golang/go/release", []byte("go1.12")); err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		if err := s.Put("repo", "golang/go/tag", []byte("go1.11")); err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		if v, err := s.Get("repo", "golang/go/release"); err != nil || string(v) != "go1.12" {
+			t.Errorf("%s: got %q %v", c.name, v, err)
+		}
+		if v, err := s.Get("other", "golang/go/release"); err != nil || v != nil {
+			t.Errorf("%s: got %q %v from other bucket", c.name, v, err)
+		}
+		if err := s.Delete("repo", "golang/go/tag"); err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		if v, err := s.Get("repo", "golang/go/tag"); err != nil || v != nil {
+			t.Errorf("%s: got %q %v after deleted", c.name, v, err)
+		}
+		if err := s.Close(); err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		if c.name == "memory" {
+			continue
+		}
+
+		// state is kept after reopened.
+		s, err = c.open()
+		if err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		if v, err := s.Get("repo", "golang/go/release"); err != nil || string(v) != "go1.12" {
+			t.Errorf("%s: got %q %v after reopened", c.name, v, err)
+		}
+		if v, err := s.Get("repo", "golang/go/tag"); err != nil || v != nil {
+			t.Errorf("%s: deleted key %q %v is back after reopened", c.name, v, err)
+		}
+		s.Close()
+	}
+}
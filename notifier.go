@@ -3,9 +3,13 @@ package watchcat
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 )
 
 // Notifier is interface of notification.
@@ -16,15 +20,22 @@ type Notifier interface {
 
 // NotificationInfo is notification payload.
 type NotificationInfo struct {
-	Owner     string
-	AvatarURL string
-	RepoName  string
-	Target    string
-	Current   string
-	Prev      string
-	Title     string
-	Body      string
-	Link      string
+	Owner     string `json:"owner"`
+	AvatarURL string `json:"avatar_url"`
+	RepoName  string `json:"repo_name"`
+	RepoURL   string `json:"repo_url"`
+	Target    string `json:"target"`
+	Current   string `json:"current"`
+	Prev      string `json:"prev"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	Link      string `json:"link"`
+	// CreatedAt is the time the item was created on github, if it is known.
+	CreatedAt time.Time `json:"created_at"`
+	// Extra has target specific fields, like number and author of issue.
+	Extra map[string]string `json:"extra,omitempty"`
+	// Message is rendered from template, and is used instead of the default text of notifiers if it is not empty.
+	Message string `json:"message,omitempty"`
 }
 
 // StdNotifier handles notifications to stdout.
@@ -32,7 +43,9 @@ type StdNotifier struct{}
 
 // SlackNotifier handles notifications to slack.
 type SlackNotifier struct {
-	WebhookURL string
+	WebhookURL string `toml:"webhook_url"`
+	// Channel overrides the channel of the webhook, if it is allowed.
+	Channel string `toml:"channel"`
 }
 
 type notifiers []Notifier
@@ -43,11 +56,21 @@ var notificationColors = map[string]string{
 	"issue":   "#EE7163",
 	"pr":      "#447DDA",
 	"tag":     "#B85FCE",
+	"digest":  "#8A8A8A",
 	"error":   "danger",
+	// state changes of pull requests.
+	"pr_merged":   "#6F42C1",
+	"pr_closed":   "#CB2431",
+	"pr_reopened": "#2CBE4E",
+	"pr_ready":    "#447DDA",
 }
 
 // Notify notifies to stdout.
 func (*StdNotifier) Notify(info *NotificationInfo) error {
+	if info.Message != "" {
+		log.Println(info.Message)
+		return nil
+	}
 	log.Printf("(%s/%s) new %s: %s\n", info.Owner, info.RepoName, info.Target, info.Link)
 
 	return nil
@@ -62,21 +85,27 @@ func (*StdNotifier) Error(err error) error {
 
 // Notify notifies to slack.
 func (n *SlackNotifier) Notify(info *NotificationInfo) error {
-	data, err := json.Marshal(map[string]interface{}{
+	return n.post(map[string]interface{}{
 		"attachments": []map[string]interface{}{
-			{
-				"fallback":    fmt.Sprintf("(%s/%s) new %s: %s", info.Owner, info.RepoName, info.Target, info.Current),
-				"author_name": fmt.Sprintf("%s/%s", info.Owner, info.RepoName),
-				"author_link": fmt.Sprintf("https://github.com/%s/%s", info.Owner, info.RepoName),
-				"author_icon": info.AvatarURL,
-				"title":       fmt.Sprintf("new %s: %s", info.Target, info.Title),
-				"title_link":  info.Link,
-				"text":        info.Body,
-				"color":       notificationColors[info.Target],
-				"mrkdwn_in":   []string{"text"},
-			},
+			slackAttachment(mrkdwnDigest(info)),
 		},
 	})
+}
+
+// Error notifies error to slack.
+func (n *SlackNotifier) Error(err error) error {
+	return n.post(map[string]interface{}{
+		"attachments": []map[string]interface{}{
+			slackErrorAttachment(err),
+		},
+	})
+}
+
+func (n *SlackNotifier) post(payload map[string]interface{}) error {
+	if n.Channel != "" {
+		payload["channel"] = n.Channel
+	}
+	data, err := json.Marshal(payload)
 	if err != nil {
 		return err
 	}
@@ -88,45 +117,144 @@ func (n *SlackNotifier) Notify(info *NotificationInfo) error {
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to notify")
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("failed to notify to slack: %s %s", res.Status, strings.TrimSpace(string(body)))
 	}
 
 	return nil
 }
 
-// Error notifies error to slack.
-func (n *SlackNotifier) Error(err error) error {
-	data, err := json.Marshal(map[string]interface{}{
-		"attachments": []map[string]interface{}{
-			{
-				"fallback": "failed",
-				"title":    "failed",
-				"text":     err.Error(),
-				"color":    notificationColors["error"],
-			},
-		},
-	})
-	if err != nil {
-		return err
+// slackAttachment builds attachment of notification for slack and slack compatible webhooks.
+func slackAttachment(info *NotificationInfo) map[string]interface{} {
+	if info.Message != "" {
+		return map[string]interface{}{
+			"fallback":    info.Message,
+			"author_name": fmt.Sprintf("%s/%s", info.Owner, info.RepoName),
+			"author_link": info.RepoURL,
+			"author_icon": info.AvatarURL,
+			"text":        info.Message,
+			"color":       notificationColors[info.Target],
+			"mrkdwn_in":   []string{"text"},
+		}
+	}
+	return map[string]interface{}{
+		"fallback":    fmt.Sprintf("(%s/%s) new %s: %s", info.Owner, info.RepoName, info.Target, info.Current),
+		"author_name": fmt.Sprintf("%s/%s", info.Owner, info.RepoName),
+		"author_link": info.RepoURL,
+		"author_icon": info.AvatarURL,
+		"title":       fmt.Sprintf("new %s: %s", info.Target, info.Title),
+		"title_link":  info.Link,
+		"text":        info.Body,
+		"color":       notificationColors[info.Target],
+		"mrkdwn_in":   []string{"text"},
 	}
+}
 
-	res, err := http.PostForm(n.WebhookURL, url.Values{"payload": {string(data)}})
-	if err != nil {
-		return err
+// slackErrorAttachment builds attachment of error for slack and slack compatible webhooks.
+func slackErrorAttachment(err error) map[string]interface{} {
+	return map[string]interface{}{
+		"fallback": "failed",
+		"title":    "failed",
+		"text":     err.Error(),
+		"color":    notificationColors["error"],
 	}
-	defer res.Body.Close()
+}
 
-	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to notify")
+// notifierName returns the name of notifier used in command line and config.
+func notifierName(n Notifier) string {
+	switch n := n.(type) {
+	case *StdNotifier:
+		return "std"
+	case *SlackNotifier:
+		return "slack"
+	case *MattermostNotifier:
+		return "mattermost"
+	case *RocketChatNotifier:
+		return "rocketchat"
+	case *DiscordNotifier:
+		return "discord"
+	case *TeamsNotifier:
+		return "teams"
+	case *EmailNotifier:
+		return "email"
+	case *TelegramNotifier:
+		return "telegram"
+	case *MatrixNotifier:
+		return "matrix"
+	case *ExecNotifier:
+		return "exec"
+	case *FeedNotifier:
+		return "feed"
+	case *FileNotifier:
+		return "file"
+	case *WebhookNotifier:
+		return "webhook"
+	case *templateNotifier:
+		return notifierName(n.Notifier)
+	case *digestNotifier:
+		return notifierName(n.current())
+	case *retryNotifier:
+		return notifierName(n.Notifier)
 	}
+	return ""
+}
 
-	return nil
+// newNotifier returns empty notifier of the name used in command line and config.
+func newNotifier(name string) (Notifier, error) {
+	switch name {
+	case "std":
+		return &StdNotifier{}, nil
+	case "slack":
+		return &SlackNotifier{}, nil
+	case "mattermost":
+		return &MattermostNotifier{}, nil
+	case "rocketchat":
+		return &RocketChatNotifier{}, nil
+	case "discord":
+		return &DiscordNotifier{}, nil
+	case "teams":
+		return &TeamsNotifier{}, nil
+	case "email":
+		return &EmailNotifier{}, nil
+	case "telegram":
+		return &TelegramNotifier{}, nil
+	case "matrix":
+		return &MatrixNotifier{}, nil
+	case "exec":
+		return &ExecNotifier{}, nil
+	case "feed":
+		return &FeedNotifier{}, nil
+	case "file":
+		return &FileNotifier{}, nil
+	case "webhook":
+		return &WebhookNotifier{}, nil
+	}
+	return nil, fmt.Errorf("invalid notifier: %s", name)
+}
+
+// truncate shortens s to at most n characters, marking it with ellipsis.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	if n <= 0 {
+		return ""
+	}
+	return string(r[:n-1]) + "…"
 }
 
 // Notify fires all notifiers' Notify.
+// failures of a notifier are reported to the other notifiers' Error.
 func (ns notifiers) Notify(info *NotificationInfo) error {
-	for _, n := range ns {
-		n.Notify(info)
+	for i, n := range ns {
+		if err := n.Notify(info); err != nil {
+			for j, o := range ns {
+				if j != i {
+					o.Error(fmt.Errorf("(%s/%s) failed to notify %s: %s", info.Owner, info.RepoName, info.Target, err))
+				}
+			}
+		}
 	}
 	return nil
 }
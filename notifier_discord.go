@@ -0,0 +1,108 @@
+package watchcat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// limits of discord embed. see https://discord.com/developers/docs/resources/channel#embed-limits
+const (
+	discordTitleLimit       = 256
+	discordDescriptionLimit = 4096
+	discordAuthorLimit      = 256
+	discordTotalLimit       = 6000
+)
+
+// discordErrorColor is used instead of "danger" which discord doesn't know.
+const discordErrorColor = 0xD00000
+
+// DiscordNotifier handles notifications to discord.
+type DiscordNotifier struct {
+	WebhookURL string `toml:"webhook_url"`
+}
+
+// Notify notifies to discord.
+func (n *DiscordNotifier) Notify(info *NotificationInfo) error {
+	author := truncate(fmt.Sprintf("%s/%s", info.Owner, info.RepoName), discordAuthorLimit)
+	title := truncate(fmt.Sprintf("new %s: %s", info.Target, info.Title), discordTitleLimit)
+	desc := info.Body
+	// rendered message replaces both title and body.
+	if info.Message != "" {
+		title = ""
+		desc = info.Message
+	}
+	// the total of all texts in embed must not exceed the limit.
+	descLimit := discordTotalLimit - len([]rune(author)) - len([]rune(title))
+	if descLimit > discordDescriptionLimit {
+		descLimit = discordDescriptionLimit
+	}
+
+	// digests have neither repository url, avatar nor link, and discord rejects empty urls.
+	embedAuthor := map[string]interface{}{"name": author}
+	if info.RepoURL != "" {
+		embedAuthor["url"] = info.RepoURL
+	}
+	if info.AvatarURL != "" {
+		embedAuthor["icon_url"] = info.AvatarURL
+	}
+	embed := map[string]interface{}{
+		"author":      embedAuthor,
+		"description": truncate(desc, descLimit),
+		"color":       discordColor(notificationColors[info.Target]),
+	}
+	if title != "" {
+		embed["title"] = title
+		if info.Link != "" {
+			embed["url"] = info.Link
+		}
+	}
+	return n.post(map[string]interface{}{
+		"embeds": []map[string]interface{}{embed},
+	})
+}
+
+// Error notifies error to discord.
+func (n *DiscordNotifier) Error(err error) error {
+	return n.post(map[string]interface{}{
+		"embeds": []map[string]interface{}{
+			{
+				"title":       "failed",
+				"description": truncate(err.Error(), discordDescriptionLimit),
+				"color":       discordErrorColor,
+			},
+		},
+	})
+}
+
+func (n *DiscordNotifier) post(payload map[string]interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	res, err := http.Post(n.WebhookURL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// discord responds 204 No Content unless wait=true is specified.
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to notify")
+	}
+
+	return nil
+}
+
+// discordColor converts color like "#3EBB3E" to integer discord accepts.
+func discordColor(color string) int {
+	c, err := strconv.ParseInt(strings.TrimPrefix(color, "#"), 16, 32)
+	if err != nil {
+		return discordErrorColor
+	}
+	return int(c)
+}
@@ -0,0 +1,289 @@
+package watchcat
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/russross/blackfriday"
+)
+
+// TLS modes of EmailNotifier.
+const (
+	// EmailTLSStartTLS requires STARTTLS.
+	EmailTLSStartTLS = "starttls"
+	// EmailTLSImplicit connects with TLS from the beginning (SMTPS).
+	EmailTLSImplicit = "tls"
+	// EmailTLSNone never uses TLS.
+	EmailTLSNone = "none"
+)
+
+// auth mechanisms of EmailNotifier.
+const (
+	EmailAuthPlain = "plain"
+	EmailAuthLogin = "login"
+)
+
+const defaultEmailSubject = "[watchcat] {{.Owner}}/{{.RepoName}} new {{.Target}}: {{.Title}}"
+
+// EmailNotifier handles notifications to email via SMTP.
+type EmailNotifier struct {
+	Host string `toml:"host"`
+	// Port defaults to 465 for implicit TLS, otherwise 587.
+	Port int `toml:"port"`
+	// TLS is "starttls", "tls" or "none".
+	// by default, STARTTLS is used only if the server supports it.
+	TLS      string `toml:"tls"`
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+	// Auth is "plain" or "login". default is "plain" if Username is specified.
+	Auth string   `toml:"auth"`
+	From string   `toml:"from"`
+	To   []string `toml:"to"`
+	// Subject is text/template of subject rendered with NotificationInfo.
+	Subject string `toml:"subject"`
+}
+
+// Notify sends notification mail.
+func (n *EmailNotifier) Notify(info *NotificationInfo) error {
+	subjectTmpl := n.Subject
+	if subjectTmpl == "" {
+		subjectTmpl = defaultEmailSubject
+	}
+	tmpl, err := template.New("subject").Parse(subjectTmpl)
+	if err != nil {
+		return err
+	}
+	var subject bytes.Buffer
+	if err := tmpl.Execute(&subject, info); err != nil {
+		return err
+	}
+
+	markdown := fmt.Sprintf("## [%s/%s](%s) new %s: [%s](%s)\n\n%s\n", info.Owner, info.RepoName, info.RepoURL, info.Target, info.Title, info.Link, info.Body)
+	plain := fmt.Sprintf("%s/%s new %s: %s\n%s\n\n%s\n", info.Owner, info.RepoName, info.Target, info.Title, info.Link, info.Body)
+	// rendered message is written in markdown as well.
+	if info.Message != "" {
+		markdown = info.Message
+		plain = info.Message
+	}
+
+	return n.send(subject.String(), plain, renderMarkdown(markdown))
+}
+
+// Error sends error mail.
+func (n *EmailNotifier) Error(err error) error {
+	return n.send("[watchcat] failed", err.Error(), renderMarkdown("## failed\n\n```\n"+err.Error()+"\n```\n"))
+}
+
+func (n *EmailNotifier) send(subject string, plain string, html []byte) error {
+	if len(n.To) == 0 {
+		return errors.New("no recipients of email")
+	}
+
+	msg, err := n.message(subject, plain, html)
+	if err != nil {
+		return err
+	}
+
+	c, err := n.dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if n.Username != "" {
+		var auth smtp.Auth
+		switch n.Auth {
+		case "", EmailAuthPlain:
+			auth = smtp.PlainAuth("", n.Username, n.Password, n.Host)
+		case EmailAuthLogin:
+			auth = &loginAuth{username: n.Username, password: n.Password, host: n.Host}
+		default:
+			return fmt.Errorf("invalid email auth: %s", n.Auth)
+		}
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(n.From); err != nil {
+		return err
+	}
+	for _, to := range n.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// dial connects to the server, upgrading connection to TLS according to the mode.
+func (n *EmailNotifier) dial() (*smtp.Client, error) {
+	port := n.Port
+	if port == 0 {
+		port = 587
+		if n.TLS == EmailTLSImplicit {
+			port = 465
+		}
+	}
+	addr := net.JoinHostPort(n.Host, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: n.Host}
+
+	switch n.TLS {
+	case EmailTLSImplicit:
+		conn, err := tls.Dial("tcp", addr, tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+		return smtp.NewClient(conn, n.Host)
+	case "", EmailTLSStartTLS, EmailTLSNone:
+	default:
+		return nil, fmt.Errorf("invalid email tls: %s", n.TLS)
+	}
+
+	c, err := smtp.Dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	if n.TLS == EmailTLSNone {
+		return c, nil
+	}
+	if ok, _ := c.Extension("STARTTLS"); !ok {
+		if n.TLS == EmailTLSStartTLS {
+			c.Close()
+			return nil, errors.New("smtp server doesn't support STARTTLS")
+		}
+		return c, nil
+	}
+	if err := c.StartTLS(tlsConfig); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// message builds multipart message with plaintext and html bodies.
+func (n *EmailNotifier) message(subject string, plain string, html []byte) ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	parts := []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=UTF-8", []byte(plain)},
+		{"text/html; charset=UTF-8", html},
+	}
+	for _, part := range parts {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qw := quotedprintable.NewWriter(pw)
+		if _, err := qw.Write(part.content); err != nil {
+			return nil, err
+		}
+		if err := qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	headers := [][2]string{
+		{"From", n.From},
+		{"To", strings.Join(n.To, ", ")},
+		{"Subject", mime.QEncoding.Encode("UTF-8", subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", messageID(n.From)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + mw.Boundary()},
+	}
+	for _, h := range headers {
+		fmt.Fprintf(&msg, "%s: %s\r\n", h[0], h[1])
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+func messageID(from string) string {
+	domain := "watchcat"
+	if i := strings.LastIndex(from, "@"); i >= 0 {
+		domain = strings.TrimRight(from[i+1:], ">")
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain)
+}
+
+// renderMarkdown renders markdown to html, skipping raw html in it.
+func renderMarkdown(markdown string) []byte {
+	renderer := blackfriday.HtmlRenderer(blackfriday.HTML_USE_XHTML|blackfriday.HTML_SKIP_HTML|blackfriday.HTML_SAFELINK, "", "")
+	extensions := blackfriday.EXTENSION_NO_INTRA_EMPHASIS |
+		blackfriday.EXTENSION_TABLES |
+		blackfriday.EXTENSION_FENCED_CODE |
+		blackfriday.EXTENSION_AUTOLINK |
+		blackfriday.EXTENSION_STRIKETHROUGH |
+		blackfriday.EXTENSION_SPACE_HEADERS
+	return blackfriday.Markdown([]byte(markdown), renderer, extensions)
+}
+
+// loginAuth implements LOGIN authentication which net/smtp doesn't provide.
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	// same as smtp.PlainAuth, never send password without TLS except to localhost.
+	if !server.TLS && a.host != "localhost" && a.host != "127.0.0.1" && a.host != "::1" {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	}
+	return nil, fmt.Errorf("unexpected server challenge: %s", fromServer)
+}
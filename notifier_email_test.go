@@ -0,0 +1,250 @@
+package watchcat
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+// fakeMail is a mail received by fakeSMTP.
+type fakeMail struct {
+	username string
+	password string
+	from     string
+	to       []string
+	data     string
+}
+
+// fakeSMTP accepts one session on a local port without TLS, and sends the received mail to the channel.
+func fakeSMTP(t *testing.T) (int, <-chan *fakeMail) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mails := make(chan *fakeMail, 1)
+
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(lines ...string) {
+			for _, line := range lines {
+				conn.Write([]byte(line + "\r\n"))
+			}
+		}
+		readLine := func() string {
+			line, _ := r.ReadString('\n')
+			return strings.TrimRight(line, "\r\n")
+		}
+		decode := func(s string) string {
+			b, _ := base64.StdEncoding.DecodeString(s)
+			return string(b)
+		}
+
+		m := &fakeMail{}
+		reply("220 fake")
+		for {
+			line := readLine()
+			switch {
+			case line == "":
+				return
+			case strings.HasPrefix(line, "EHLO"):
+				reply("250-fake", "250 AUTH PLAIN LOGIN")
+			case strings.HasPrefix(line, "AUTH LOGIN"):
+				reply("334 " + base64.StdEncoding.EncodeToString([]byte("Username:")))
+				m.username = decode(readLine())
+				reply("334 " + base64.StdEncoding.EncodeToString([]byte("Password:")))
+				m.password = decode(readLine())
+				reply("235 ok")
+			case strings.HasPrefix(line, "AUTH PLAIN "):
+				parts := strings.Split(decode(strings.TrimPrefix(line, "AUTH PLAIN ")), "\x00")
+				if len(parts) == 3 {
+					m.username, m.password = parts[1], parts[2]
+				}
+				reply("235 ok")
+			case strings.HasPrefix(line, "MAIL FROM:"):
+				m.from = strings.Trim(strings.TrimPrefix(line, "MAIL FROM:"), "<>")
+				reply("250 ok")
+			case strings.HasPrefix(line, "RCPT TO:"):
+				m.to = append(m.to, strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>"))
+				reply("250 ok")
+			case line == "DATA":
+				reply("354 go ahead")
+				var data []string
+				for {
+					l := readLine()
+					if l == "." {
+						break
+					}
+					data = append(data, l)
+				}
+				m.data = strings.Join(data, "\r\n") + "\r\n"
+				reply("250 ok")
+			case line == "QUIT":
+				reply("221 bye")
+				mails <- m
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	return l.Addr().(*net.TCPAddr).Port, mails
+}
+
+// parseMail returns decoded subject and bodies of the message by content type.
+func parseMail(t *testing.T, data string) (string, map[string]string) {
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("unexpected content type: %s %v", mediaType, err)
+	}
+
+	bodies := map[string]string{}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		b, err := ioutil.ReadAll(quotedprintable.NewReader(part))
+		if err != nil {
+			t.Fatal(err)
+		}
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		bodies[contentType] = string(b)
+	}
+	return subject, bodies
+}
+
+func TestEmailNotifierNotify(t *testing.T) {
+	for _, auth := range []string{EmailAuthPlain, EmailAuthLogin} {
+		port, mails := fakeSMTP(t)
+		n := &EmailNotifier{
+			Host:     "127.0.0.1",
+			Port:     port,
+			TLS:      EmailTLSNone,
+			Username: "user",
+			Password: "pass",
+			Auth:     auth,
+			From:     "watchcat@example.com",
+			To:       []string{"a@example.com", "b@example.com"},
+		}
+		err := n.Notify(&NotificationInfo{
+			Owner:    "golang",
+			RepoName: "go",
+			Target:   TargetRelease,
+			Title:    "go1.12 – released",
+			Body:     "**bold** <script>alert(1)</script>\n\n- a\n- b",
+			Link:     "https://github.com/golang/go/releases/tag/go1.12",
+		})
+		if err != nil {
+			t.Fatalf("%s: %s", auth, err)
+		}
+
+		m := <-mails
+		if m.username != "user" || m.password != "pass" {
+			t.Errorf("%s: unexpected credentials: %s %s", auth, m.username, m.password)
+		}
+		if m.from != "watchcat@example.com" || strings.Join(m.to, ",") != "a@example.com,b@example.com" {
+			t.Errorf("%s: unexpected envelope: %s %v", auth, m.from, m.to)
+		}
+
+		subject, bodies := parseMail(t, m.data)
+		if want := "[watchcat] golang/go new release: go1.12 – released"; subject != want {
+			t.Errorf("%s: subject = %q, want %q", auth, subject, want)
+		}
+		if !strings.Contains(bodies["text/plain"], "**bold**") {
+			t.Errorf("%s: unexpected plain body: %q", auth, bodies["text/plain"])
+		}
+		html := bodies["text/html"]
+		if !strings.Contains(html, "<strong>bold</strong>") || !strings.Contains(html, "<li>a</li>") {
+			t.Errorf("%s: markdown is not rendered: %q", auth, html)
+		}
+		if strings.Contains(html, "<script>") {
+			t.Errorf("%s: raw html is not skipped: %q", auth, html)
+		}
+	}
+}
+
+func TestEmailNotifierError(t *testing.T) {
+	port, mails := fakeSMTP(t)
+	n := &EmailNotifier{
+		Host: "127.0.0.1",
+		Port: port,
+		TLS:  EmailTLSNone,
+		From: "watchcat@example.com",
+		To:   []string{"a@example.com"},
+	}
+	if err := n.Error(errors.New("rate limited")); err != nil {
+		t.Fatal(err)
+	}
+
+	m := <-mails
+	if m.username != "" {
+		t.Errorf("authenticated without username: %s", m.username)
+	}
+	subject, bodies := parseMail(t, m.data)
+	if subject != "[watchcat] failed" || !strings.Contains(bodies["text/plain"], "rate limited") {
+		t.Errorf("unexpected error mail: %q %q", subject, bodies["text/plain"])
+	}
+}
+
+func TestEmailNotifierSubject(t *testing.T) {
+	port, mails := fakeSMTP(t)
+	n := &EmailNotifier{
+		Host:    "127.0.0.1",
+		Port:    port,
+		TLS:     EmailTLSNone,
+		From:    "watchcat@example.com",
+		To:      []string{"a@example.com"},
+		Subject: "{{.RepoName}} {{.Current}}",
+	}
+	if err := n.Notify(&NotificationInfo{RepoName: "go", Current: "go1.12", Message: "rendered *message*"}); err != nil {
+		t.Fatal(err)
+	}
+
+	subject, bodies := parseMail(t, (<-mails).data)
+	if subject != "go go1.12" {
+		t.Errorf("subject = %q", subject)
+	}
+	if bodies["text/plain"] != "rendered *message*" || !strings.Contains(bodies["text/html"], "<em>message</em>") {
+		t.Errorf("rendered message is not used: %q", bodies)
+	}
+}
+
+func TestEmailNotifierInvalid(t *testing.T) {
+	cases := []struct {
+		name string
+		n    *EmailNotifier
+	}{
+		{"no recipients", &EmailNotifier{Host: "127.0.0.1", From: "watchcat@example.com"}},
+		{"invalid tls", &EmailNotifier{Host: "127.0.0.1", TLS: "ssl", To: []string{"a@example.com"}}},
+	}
+	for _, c := range cases {
+		if err := c.n.Error(errors.New("x")); err == nil {
+			t.Errorf("%s: no error", c.name)
+		}
+	}
+}
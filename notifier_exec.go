@@ -0,0 +1,125 @@
+package watchcat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultExecTimeout is used when ExecNotifier.Timeout is not specified.
+const defaultExecTimeout = 1 * time.Minute
+
+// maxExecStderr is the length of stderr included in error.
+const maxExecStderr = 1000
+
+// ExecNotifier handles notifications by running local command.
+// the fields of notification are exposed as WATCHCAT_* environment variables,
+// and the same json as WebhookPayload is written to stdin.
+type ExecNotifier struct {
+	// Command is the path of executable and its arguments.
+	Command []string      `toml:"command"`
+	Timeout time.Duration `toml:"-"`
+	// Concurrency limits the number of commands running at the same time. default is 1.
+	Concurrency int `toml:"concurrency"`
+
+	once sync.Once
+	sem  chan struct{}
+}
+
+// Notify runs command with notification.
+func (n *ExecNotifier) Notify(info *NotificationInfo) error {
+	return n.run(&WebhookPayload{
+		Event:        EventNotification,
+		SentAt:       time.Now(),
+		Notification: info,
+	})
+}
+
+// Error runs command with error.
+func (n *ExecNotifier) Error(err error) error {
+	return n.run(&WebhookPayload{
+		Event:  EventError,
+		SentAt: time.Now(),
+		Error:  err.Error(),
+	})
+}
+
+func (n *ExecNotifier) run(payload *WebhookPayload) error {
+	if len(n.Command) == 0 {
+		return fmt.Errorf("not specified command")
+	}
+
+	n.once.Do(func() {
+		concurrency := n.Concurrency
+		if concurrency <= 0 {
+			concurrency = 1
+		}
+		n.sem = make(chan struct{}, concurrency)
+	})
+	n.sem <- struct{}{}
+	defer func() { <-n.sem }()
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	timeout := n.Timeout
+	if timeout <= 0 {
+		timeout = defaultExecTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, n.Command[0], n.Command[1:]...)
+	cmd.Env = append(os.Environ(), execEnv(payload)...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %s", timeout)
+		}
+		msg := fmt.Sprintf("failed to run %s: %s", n.Command[0], err)
+		if s := strings.TrimSpace(stderr.String()); s != "" {
+			msg += "\n" + truncate(s, maxExecStderr)
+		}
+		return errors.New(msg)
+	}
+
+	return nil
+}
+
+// execEnv builds WATCHCAT_* environment variables from payload.
+func execEnv(payload *WebhookPayload) []string {
+	env := []string{
+		"WATCHCAT_EVENT=" + payload.Event,
+	}
+	if payload.Error != "" {
+		env = append(env, "WATCHCAT_ERROR="+payload.Error)
+	}
+	if info := payload.Notification; info != nil {
+		env = append(env,
+			"WATCHCAT_OWNER="+info.Owner,
+			"WATCHCAT_AVATAR_URL="+info.AvatarURL,
+			"WATCHCAT_REPO_NAME="+info.RepoName,
+			"WATCHCAT_REPO_URL="+info.RepoURL,
+			"WATCHCAT_TARGET="+info.Target,
+			"WATCHCAT_CURRENT="+info.Current,
+			"WATCHCAT_PREV="+info.Prev,
+			"WATCHCAT_TITLE="+info.Title,
+			"WATCHCAT_BODY="+info.Body,
+			"WATCHCAT_LINK="+info.Link,
+			"WATCHCAT_MESSAGE="+info.Message,
+		)
+	}
+	return env
+}
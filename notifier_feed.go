@@ -0,0 +1,330 @@
+package watchcat
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kudohamu/watchcat/internal/lmdb"
+)
+
+// defaultFeedSize is used when FeedNotifier.Size is not specified.
+const defaultFeedSize = 50
+
+// defaultFeedAddr is used when FeedNotifier.Addr is not specified.
+// feeds are served only on loopback by default, because they expose activity of watched repositories.
+const defaultFeedAddr = "localhost:8080"
+
+const bktFeed = "feed"
+
+// FeedNotifier keeps the last notifications and serves them as atom and rss feeds.
+// feeds of all repositories are served at /feed.atom and /feed.rss,
+// feeds of a repository at /{owner}/{repo}/feed.atom and feeds of a target at /{owner}/{repo}/{target}/feed.atom.
+type FeedNotifier struct {
+	// Addr is the address Watcher serves feeds on.
+	Addr string `toml:"addr"`
+	// Size is the number of notifications kept in each feed.
+	Size int `toml:"size"`
+
+	mu    sync.Mutex
+	store lmdb.Store
+}
+
+// feedEntry is a notification kept in feeds.
+type feedEntry struct {
+	Info       *NotificationInfo `json:"info"`
+	NotifiedAt time.Time         `json:"notified_at"`
+}
+
+// Notify appends notification to feeds of all repositories, the repository and the target.
+// every feed keeps its own last entries, so that busy repositories never push out entries of others.
+func (n *FeedNotifier) Notify(info *NotificationInfo) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	entry := &feedEntry{Info: info, NotifiedAt: time.Now()}
+	size := n.Size
+	if size <= 0 {
+		size = defaultFeedSize
+	}
+	for _, key := range []string{
+		feedKey("", "", ""),
+		feedKey(info.Owner, info.RepoName, ""),
+		feedKey(info.Owner, info.RepoName, info.Target),
+	} {
+		entries, err := n.entries(key)
+		if err != nil {
+			return err
+		}
+		entries = append([]*feedEntry{entry}, entries...)
+		if len(entries) > size {
+			entries = entries[:size]
+		}
+
+		data, err := json.Marshal(entries)
+		if err != nil {
+			return err
+		}
+		if err := n.getStore().Put(bktFeed, key, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Error does nothing because feeds contain only notifications.
+func (*FeedNotifier) Error(err error) error {
+	return nil
+}
+
+// ServeHTTP serves atom or rss feed.
+func (n *FeedNotifier) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	segs := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	format := segs[len(segs)-1]
+	if format != "feed.atom" && format != "feed.rss" {
+		http.NotFound(w, r)
+		return
+	}
+	var owner, repo, target string
+	switch len(segs) {
+	case 1:
+	case 3:
+		owner, repo = segs[0], segs[1]
+	case 4:
+		owner, repo, target = segs[0], segs[1], segs[2]
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	n.mu.Lock()
+	entries, err := n.entries(feedKey(owner, repo, target))
+	n.mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	title := "watchcat"
+	if owner != "" {
+		title = fmt.Sprintf("watchcat: %s/%s", owner, repo)
+	}
+	if target != "" {
+		title = fmt.Sprintf("%s %s", title, target)
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	self := fmt.Sprintf("%s://%s%s", scheme, r.Host, r.URL.Path)
+
+	var feed interface{}
+	if format == "feed.atom" {
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		feed = atomFeedOf(title, self, entries)
+	} else {
+		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+		feed = rssFeedOf(title, self, entries)
+	}
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write([]byte(xml.Header))
+	w.Write(data)
+}
+
+// serve starts to serve feeds on Addr with state in store.
+func (n *FeedNotifier) serve(store lmdb.Store) (*http.Server, error) {
+	n.mu.Lock()
+	n.store = store
+	n.mu.Unlock()
+
+	addr := n.Addr
+	if addr == "" {
+		addr = defaultFeedAddr
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	srv := &http.Server{Handler: n}
+	go srv.Serve(ln)
+	return srv, nil
+}
+
+// getStore returns store to keep entries, which is on memory until Watcher serves feeds.
+func (n *FeedNotifier) getStore() lmdb.Store {
+	if n.store == nil {
+		n.store = lmdb.NewMemoryStore()
+	}
+	return n.store
+}
+
+// feedKey returns key of entries of the feed, which is of all repositories if owner is empty.
+func feedKey(owner string, repo string, target string) string {
+	switch {
+	case owner == "":
+		return "entries"
+	case target == "":
+		return fmt.Sprintf("entries:%s/%s", owner, repo)
+	}
+	return fmt.Sprintf("entries:%s/%s/%s", owner, repo, target)
+}
+
+// entries returns kept entries of the feed of key, newest first.
+func (n *FeedNotifier) entries(key string) ([]*feedEntry, error) {
+	data, err := n.getStore().Get(bktFeed, key)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var entries []*feedEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (e *feedEntry) id() string {
+	return fmt.Sprintf("tag:watchcat,2017:%s/%s/%s/%s", e.Info.Owner, e.Info.RepoName, e.Info.Target, e.Info.Current)
+}
+
+func (e *feedEntry) title() string {
+	return fmt.Sprintf("(%s/%s) new %s: %s", e.Info.Owner, e.Info.RepoName, e.Info.Target, e.Info.Title)
+}
+
+// content returns rendered message if it exists, or body.
+func (e *feedEntry) content() string {
+	if e.Info.Message != "" {
+		return e.Info.Message
+	}
+	return e.Info.Body
+}
+
+type atomFeed struct {
+	XMLName xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string       `xml:"id"`
+	Title   string       `xml:"title"`
+	Updated string       `xml:"updated"`
+	Link    atomLink     `xml:"link"`
+	Entries []*atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    *atomLink   `xml:"link,omitempty"`
+	Author  *atomAuthor `xml:"author"`
+	Content *atomText   `xml:"content,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+func atomFeedOf(title string, self string, entries []*feedEntry) *atomFeed {
+	// updated is required even if feed is empty.
+	updated := time.Unix(0, 0)
+	if len(entries) > 0 {
+		updated = entries[0].NotifiedAt
+	}
+	feed := &atomFeed{
+		ID:      self,
+		Title:   title,
+		Updated: updated.Format(time.RFC3339),
+		Link:    atomLink{Href: self, Rel: "self"},
+	}
+	for _, e := range entries {
+		entry := &atomEntry{
+			ID:      e.id(),
+			Title:   e.title(),
+			Updated: e.NotifiedAt.Format(time.RFC3339),
+			Author: &atomAuthor{
+				Name: fmt.Sprintf("%s/%s", e.Info.Owner, e.Info.RepoName),
+				URI:  e.Info.RepoURL,
+			},
+		}
+		if e.Info.Link != "" {
+			entry.Link = &atomLink{Href: e.Info.Link}
+		}
+		if e.content() != "" {
+			entry.Content = &atomText{Type: "text", Body: e.content()}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string     `xml:"title"`
+	Link          string     `xml:"link"`
+	Description   string     `xml:"description"`
+	LastBuildDate string     `xml:"lastBuildDate,omitempty"`
+	Items         []*rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Description string  `xml:"description,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func rssFeedOf(title string, self string, entries []*feedEntry) *rssFeed {
+	feed := &rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       title,
+			Link:        self,
+			Description: title,
+		},
+	}
+	if len(entries) > 0 {
+		feed.Channel.LastBuildDate = entries[0].NotifiedAt.Format(time.RFC1123Z)
+	}
+	for _, e := range entries {
+		feed.Channel.Items = append(feed.Channel.Items, &rssItem{
+			Title:       e.title(),
+			Link:        e.Info.Link,
+			GUID:        rssGUID{Value: e.id()},
+			PubDate:     e.NotifiedAt.Format(time.RFC1123Z),
+			Description: e.content(),
+		})
+	}
+	return feed
+}
@@ -0,0 +1,86 @@
+package watchcat
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kudohamu/watchcat/internal/lmdb"
+)
+
+func TestFeedNotifierServeHTTP(t *testing.T) {
+	store := lmdb.NewMemoryStore()
+	n := &FeedNotifier{Size: 2, store: store}
+	for _, info := range []*NotificationInfo{
+		{Owner: "golang", RepoName: "go", Target: TargetRelease, Title: "go1.11", Current: "go1.11", Link: "https://github.com/golang/go/releases/tag/go1.11"},
+		{Owner: "golang", RepoName: "go", Target: TargetIssue, Title: "crash", Current: "1"},
+		{Owner: "golang", RepoName: "go", Target: TargetRelease, Title: "go1.12", Current: "go1.12", Body: "released"},
+		{Owner: "docker", RepoName: "cli", Target: TargetRelease, Title: "v18.09", Current: "v18.09"},
+	} {
+		if err := n.Notify(info); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		method string
+		path   string
+		code   int
+		titles []string
+	}{
+		{"GET", "/feed.atom", 200, []string{"(docker/cli) new release: v18.09", "(golang/go) new release: go1.12"}},
+		{"GET", "/golang/go/feed.atom", 200, []string{"(golang/go) new release: go1.12", "(golang/go) new issue: crash"}},
+		// every feed keeps its own entries, so the issue doesn't push the first release out.
+		{"GET", "/golang/go/release/feed.rss", 200, []string{"(golang/go) new release: go1.12", "(golang/go) new release: go1.11"}},
+		{"GET", "/kudohamu/watchcat/feed.atom", 200, nil},
+		{"GET", "/golang/feed.atom", 404, nil},
+		{"GET", "/golang/go/release", 404, nil},
+		{"POST", "/feed.atom", 405, nil},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		n.ServeHTTP(rec, httptest.NewRequest(c.method, c.path, nil))
+		if rec.Code != c.code {
+			t.Errorf("%s %s: code = %d, want %d", c.method, c.path, rec.Code, c.code)
+			continue
+		}
+		if c.code != http.StatusOK {
+			continue
+		}
+
+		var titles []string
+		if strings.HasSuffix(c.path, ".atom") {
+			var feed atomFeed
+			if err := xml.Unmarshal(rec.Body.Bytes(), &feed); err != nil {
+				t.Fatalf("%s: %s", c.path, err)
+			}
+			for _, e := range feed.Entries {
+				titles = append(titles, e.Title)
+			}
+		} else {
+			var feed rssFeed
+			if err := xml.Unmarshal(rec.Body.Bytes(), &feed); err != nil {
+				t.Fatalf("%s: %s", c.path, err)
+			}
+			for _, item := range feed.Channel.Items {
+				titles = append(titles, item.Title)
+			}
+		}
+		if fmt.Sprint(titles) != fmt.Sprint(c.titles) {
+			t.Errorf("%s: titles = %q, want %q", c.path, titles, c.titles)
+		}
+	}
+
+	// entries are kept in the store, so they survive restarts.
+	restarted := &FeedNotifier{store: store}
+	entries, err := restarted.entries(feedKey("golang", "go", TargetRelease))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].content() != "released" || entries[1].Info.Link == "" {
+		t.Errorf("unexpected entries after restart: %d", len(entries))
+	}
+}
@@ -0,0 +1,160 @@
+package watchcat
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultFileMaxSize is used when FileNotifier.MaxSize is not specified.
+const defaultFileMaxSize = 100 * 1024 * 1024
+
+// FileNotifier appends notifications and errors to file as json lines.
+// every line is the same json as WebhookPayload.
+// the file is rotated when it exceeds MaxSize, and rotated files are gzipped as {Path}.{timestamp}.gz.
+type FileNotifier struct {
+	Path string `toml:"path"`
+	// MaxSize is the size in bytes to rotate the file. default is 100MB.
+	MaxSize int64 `toml:"max_size"`
+	// MaxBackups is the number of rotated files to keep. all files are kept if it is 0.
+	MaxBackups int `toml:"max_backups"`
+
+	mu sync.Mutex
+}
+
+// Notify appends notification to the file.
+func (n *FileNotifier) Notify(info *NotificationInfo) error {
+	return n.write(&WebhookPayload{
+		Event:        EventNotification,
+		SentAt:       time.Now(),
+		Notification: info,
+	})
+}
+
+// Error appends error to the file.
+func (n *FileNotifier) Error(err error) error {
+	return n.write(&WebhookPayload{
+		Event:  EventError,
+		SentAt: time.Now(),
+		Error:  err.Error(),
+	})
+}
+
+func (n *FileNotifier) write(payload *WebhookPayload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	p, err := expandHome(n.Path)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+
+	maxSize := n.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultFileMaxSize
+	}
+	rotated := false
+	if fi, err := os.Stat(p); err == nil && fi.Size() > 0 && fi.Size()+int64(len(data)) > maxSize {
+		if err := os.Rename(p, p+"."+time.Now().Format("20060102T150405.000")); err != nil {
+			return err
+		}
+		rotated = true
+	}
+
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	// the line is already written, so failing to compress doesn't fail the notification.
+	// rotated files left uncompressed are compressed at the next rotation.
+	if rotated {
+		if err := n.compress(p); err != nil {
+			log.Printf("failed to compress rotated files of %s: %s\n", p, err)
+		}
+	}
+	return nil
+}
+
+// compress gzips rotated files of the file at p and removes old ones.
+func (n *FileNotifier) compress(p string) error {
+	rotated, err := filepath.Glob(p + ".[0-9]*")
+	if err != nil {
+		return err
+	}
+	for _, r := range rotated {
+		if strings.HasSuffix(r, ".gz") {
+			continue
+		}
+		if err := gzipFile(r); err != nil {
+			return err
+		}
+	}
+
+	if n.MaxBackups <= 0 {
+		return nil
+	}
+	backups, err := filepath.Glob(p + ".*.gz")
+	if err != nil {
+		return err
+	}
+	// timestamp in the name sorts backups from oldest.
+	sort.Strings(backups)
+	for len(backups) > n.MaxBackups {
+		os.Remove(backups[0])
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// gzipFile compresses the file at p into p.gz and removes p.
+func gzipFile(p string) error {
+	src, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(p+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	src.Close()
+	return os.Remove(p)
+}
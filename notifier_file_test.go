@@ -0,0 +1,98 @@
+package watchcat
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+)
+
+// fileLines returns currents of notifications written in the file, which is gzipped if the name ends with .gz.
+func fileLines(t *testing.T, p string) []string {
+	f, err := os.Open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if filepath.Ext(p) == ".gz" {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = zr
+	}
+	var currents []string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		var payload WebhookPayload
+		if err := json.Unmarshal(s.Bytes(), &payload); err != nil {
+			t.Fatal(err)
+		}
+		currents = append(currents, payload.Notification.Current)
+	}
+	return currents
+}
+
+func TestFileNotifierRotate(t *testing.T) {
+	backupName := regexp.MustCompile(`^notify\.log\.\d{8}T\d{6}\.\d{3}\.gz$`)
+	cases := []struct {
+		name       string
+		maxSize    int64
+		maxBackups int
+		writes     int
+		current    []string
+		backups    [][]string
+	}{
+		{"under size", 0, 0, 3, []string{"0", "1", "2"}, nil},
+		{"rotated", 1, 0, 3, []string{"2"}, [][]string{{"0"}, {"1"}}},
+		{"pruned", 1, 1, 4, []string{"3"}, [][]string{{"2"}}},
+	}
+	for _, c := range cases {
+		dir, err := ioutil.TempDir("", "watchcat")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		p := filepath.Join(dir, "logs", "notify.log")
+		n := &FileNotifier{Path: p, MaxSize: c.maxSize, MaxBackups: c.maxBackups}
+		for i := 0; i < c.writes; i++ {
+			if err := n.Notify(&NotificationInfo{Current: fmt.Sprint(i)}); err != nil {
+				t.Fatalf("%s: %s", c.name, err)
+			}
+			// rotated files are named by milliseconds.
+			time.Sleep(2 * time.Millisecond)
+		}
+
+		if got := fileLines(t, p); fmt.Sprint(got) != fmt.Sprint(c.current) {
+			t.Errorf("%s: file has %v, want %v", c.name, got, c.current)
+		}
+		files, err := filepath.Glob(p + ".*")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(files)
+		if len(files) != len(c.backups) {
+			t.Errorf("%s: %d backups, want %d: %v", c.name, len(files), len(c.backups), files)
+			continue
+		}
+		for i, f := range files {
+			if !backupName.MatchString(filepath.Base(f)) {
+				t.Errorf("%s: unexpected backup name %s", c.name, filepath.Base(f))
+			}
+			if got := fileLines(t, f); fmt.Sprint(got) != fmt.Sprint(c.backups[i]) {
+				t.Errorf("%s: backup %s has %v, want %v", c.name, filepath.Base(f), got, c.backups[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package watchcat
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// MatrixNotifier handles notifications to matrix room.
+type MatrixNotifier struct {
+	// HomeserverURL is the url of homeserver, like https://matrix.org.
+	HomeserverURL string `toml:"homeserver_url"`
+	AccessToken   string `toml:"access_token"`
+	RoomID        string `toml:"room_id"`
+}
+
+// Notify notifies to matrix room.
+func (n *MatrixNotifier) Notify(info *NotificationInfo) error {
+	plain := fmt.Sprintf("%s/%s new %s: %s\n%s", info.Owner, info.RepoName, info.Target, info.Title, info.Link)
+	formatted := fmt.Sprintf(`<b><a href="%s">%s/%s</a></b> new %s: <a href="%s">%s</a>`,
+		html.EscapeString(info.RepoURL),
+		html.EscapeString(info.Owner),
+		html.EscapeString(info.RepoName),
+		html.EscapeString(info.Target),
+		html.EscapeString(info.Link),
+		html.EscapeString(info.Title),
+	)
+	if info.Body != "" {
+		plain += "\n\n" + info.Body
+		formatted += "\n" + string(renderMarkdown(info.Body))
+	}
+	// rendered message is written in markdown.
+	if info.Message != "" {
+		plain = info.Message
+		formatted = string(renderMarkdown(info.Message))
+	}
+
+	// the same notification always has the same transaction id, so that homeserver ignores its redeliveries.
+	// the rendered bodies are hashed as well, so that different messages of the same item (like digests) never share it.
+	h := sha256.Sum256([]byte(strings.Join([]string{info.Owner, info.RepoName, info.Target, info.Current, plain, formatted}, "\x00")))
+	return n.send("watchcat-"+hex.EncodeToString(h[:16]), plain, formatted)
+}
+
+// Error notifies error to matrix room.
+func (n *MatrixNotifier) Error(err error) error {
+	// errors are never redelivered, so they have random transaction ids.
+	b := make([]byte, 16)
+	rand.Read(b)
+	return n.send("watchcat-error-"+hex.EncodeToString(b),
+		"failed: "+err.Error(),
+		"<b>failed</b>\n<pre><code>"+html.EscapeString(err.Error())+"</code></pre>",
+	)
+}
+
+// send sends m.room.message event with the transaction id.
+func (n *MatrixNotifier) send(txnID string, plain string, formatted string) error {
+	data, err := json.Marshal(map[string]string{
+		"msgtype":        "m.notice",
+		"body":           plain,
+		"format":         "org.matrix.custom.html",
+		"formatted_body": formatted,
+	})
+	if err != nil {
+		return err
+	}
+
+	u := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		strings.TrimSuffix(n.HomeserverURL, "/"),
+		url.PathEscape(n.RoomID),
+		url.PathEscape(txnID),
+	)
+
+	req, err := http.NewRequest(http.MethodPut, u, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+n.AccessToken)
+
+	client := http.Client{Timeout: 20 * time.Second}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var body struct {
+			ErrCode string `json:"errcode"`
+			Error   string `json:"error"`
+		}
+		json.NewDecoder(res.Body).Decode(&body)
+		return fmt.Errorf("failed to notify to matrix: %s %s %s", res.Status, body.ErrCode, body.Error)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package watchcat
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+)
+
+// fakeHomeserver records transaction ids of sent messages, and fails with status if it is set.
+type fakeHomeserver struct {
+	txnIDs []string
+	status int
+}
+
+func (f *fakeHomeserver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.txnIDs = append(f.txnIDs, path.Base(r.URL.Path))
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		w.Write([]byte(`{"errcode":"M_UNKNOWN","error":"unavailable"}`))
+		return
+	}
+	w.Write([]byte(`{"event_id":"$1"}`))
+}
+
+func TestMatrixNotifierTxnID(t *testing.T) {
+	info := func(current string, message string) *NotificationInfo {
+		return &NotificationInfo{Owner: "watchcat", RepoName: TargetDigest, Target: TargetDigest, Current: current, Title: current + " new events", Message: message}
+	}
+	cases := []struct {
+		name string
+		a    *NotificationInfo
+		b    *NotificationInfo
+		same bool
+	}{
+		{"redelivery", info("2", "a, b"), info("2", "a, b"), true},
+		{"other body", info("2", "a, b"), info("2", "c, d"), false},
+		{"other item", info("2", "a, b"), info("3", "a, b"), false},
+	}
+	for _, c := range cases {
+		fake := &fakeHomeserver{}
+		srv := httptest.NewServer(fake)
+		n := &MatrixNotifier{HomeserverURL: srv.URL, AccessToken: "token", RoomID: "!room:example.com"}
+		if err := n.Notify(c.a); err != nil {
+			t.Fatal(err)
+		}
+		if err := n.Notify(c.b); err != nil {
+			t.Fatal(err)
+		}
+		srv.Close()
+		if (fake.txnIDs[0] == fake.txnIDs[1]) != c.same {
+			t.Errorf("%s: transaction ids %q", c.name, fake.txnIDs)
+		}
+	}
+}
+
+func TestMatrixNotifierFailure(t *testing.T) {
+	fake := &fakeHomeserver{status: http.StatusServiceUnavailable}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	n := &MatrixNotifier{HomeserverURL: srv.URL, AccessToken: "token", RoomID: "!room:example.com"}
+	if err := n.Notify(&NotificationInfo{Owner: "o", RepoName: "n", Target: TargetIssue}); err == nil {
+		t.Error("no error")
+	}
+	// failed messages are retried by the retry queue instead of the notifier.
+	if len(fake.txnIDs) != 1 {
+		t.Errorf("sent %d times", len(fake.txnIDs))
+	}
+
+	n.Error(errors.New("a"))
+	n.Error(errors.New("a"))
+	if fake.txnIDs[1] == fake.txnIDs[2] {
+		t.Error("errors share transaction id")
+	}
+}
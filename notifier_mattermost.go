@@ -0,0 +1,73 @@
+package watchcat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// errorColor is used instead of "danger" for servers which accept only hex colors.
+const errorColor = "#D00000"
+
+// MattermostNotifier handles notifications to mattermost.
+type MattermostNotifier struct {
+	WebhookURL string `toml:"webhook_url"`
+	// Username and IconURL override the name and icon of the webhook, if it is allowed on the server.
+	Username string `toml:"username"`
+	IconURL  string `toml:"icon_url"`
+	// Channel overrides the channel of the webhook.
+	Channel string `toml:"channel"`
+}
+
+// Notify notifies to mattermost.
+func (n *MattermostNotifier) Notify(info *NotificationInfo) error {
+	return n.post(mattermostAttachment(slackAttachment(info)))
+}
+
+// Error notifies error to mattermost.
+func (n *MattermostNotifier) Error(err error) error {
+	return n.post(mattermostAttachment(slackErrorAttachment(err)))
+}
+
+func (n *MattermostNotifier) post(attachment map[string]interface{}) error {
+	payload := map[string]interface{}{
+		"attachments": []map[string]interface{}{attachment},
+	}
+	if n.Username != "" {
+		payload["username"] = n.Username
+	}
+	if n.IconURL != "" {
+		payload["icon_url"] = n.IconURL
+	}
+	if n.Channel != "" {
+		payload["channel"] = n.Channel
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	res, err := http.Post(n.WebhookURL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to notify")
+	}
+
+	return nil
+}
+
+// mattermostAttachment converts slack attachment to mattermost dialect.
+// mattermost always renders markdown, and accepts only hex colors.
+func mattermostAttachment(attachment map[string]interface{}) map[string]interface{} {
+	delete(attachment, "mrkdwn_in")
+	if attachment["color"] == notificationColors["error"] {
+		attachment["color"] = errorColor
+	}
+	return attachment
+}
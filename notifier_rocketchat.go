@@ -0,0 +1,79 @@
+package watchcat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// RocketChatNotifier handles notifications to rocket.chat.
+type RocketChatNotifier struct {
+	WebhookURL string `toml:"webhook_url"`
+	// Alias and AvatarURL override the name and avatar of the webhook.
+	Alias     string `toml:"alias"`
+	AvatarURL string `toml:"avatar_url"`
+	// Channel overrides the channel of the webhook.
+	Channel string `toml:"channel"`
+}
+
+// Notify notifies to rocket.chat.
+func (n *RocketChatNotifier) Notify(info *NotificationInfo) error {
+	attachment := slackAttachment(mrkdwnDigest(info))
+	return n.post(attachment["fallback"].(string), rocketChatAttachment(attachment))
+}
+
+// Error notifies error to rocket.chat.
+func (n *RocketChatNotifier) Error(err error) error {
+	return n.post("failed", rocketChatAttachment(slackErrorAttachment(err)))
+}
+
+func (n *RocketChatNotifier) post(text string, attachment map[string]interface{}) error {
+	payload := map[string]interface{}{
+		"text":        text,
+		"attachments": []map[string]interface{}{attachment},
+	}
+	if n.Alias != "" {
+		payload["alias"] = n.Alias
+	}
+	if n.AvatarURL != "" {
+		payload["avatar"] = n.AvatarURL
+	}
+	if n.Channel != "" {
+		payload["channel"] = n.Channel
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	res, err := http.Post(n.WebhookURL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// rocket.chat reports failure of integration script in the body.
+	var body struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+	}
+	json.NewDecoder(res.Body).Decode(&body)
+	if res.StatusCode != http.StatusOK || !body.Success {
+		return fmt.Errorf("failed to notify to rocket.chat: %s %s", res.Status, body.Error)
+	}
+
+	return nil
+}
+
+// rocketChatAttachment converts slack attachment to rocket.chat dialect.
+// rocket.chat shows message text instead of fallback, and accepts only hex colors.
+func rocketChatAttachment(attachment map[string]interface{}) map[string]interface{} {
+	delete(attachment, "fallback")
+	delete(attachment, "mrkdwn_in")
+	if attachment["color"] == notificationColors["error"] {
+		attachment["color"] = errorColor
+	}
+	return attachment
+}
@@ -0,0 +1,166 @@
+package watchcat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// TeamsNotifier handles notifications to microsoft teams with adaptive cards.
+// WebhookURL is the url of incoming webhook or workflows.
+type TeamsNotifier struct {
+	WebhookURL string `toml:"webhook_url"`
+}
+
+// Notify notifies to teams.
+func (n *TeamsNotifier) Notify(info *NotificationInfo) error {
+	repoItems := []map[string]interface{}{}
+	if info.AvatarURL != "" {
+		repoItems = append(repoItems, map[string]interface{}{
+			"type":  "Image",
+			"url":   info.AvatarURL,
+			"size":  "Small",
+			"style": "Person",
+		})
+	}
+
+	// digests have neither repository url nor link.
+	repo := fmt.Sprintf("%s/%s", info.Owner, info.RepoName)
+	if info.RepoURL != "" {
+		repo = fmt.Sprintf("[%s](%s)", repo, info.RepoURL)
+	}
+
+	body := []map[string]interface{}{
+		{
+			"type": "ColumnSet",
+			"columns": []map[string]interface{}{
+				{
+					"type":  "Column",
+					"width": "auto",
+					"items": repoItems,
+				},
+				{
+					"type":                     "Column",
+					"width":                    "stretch",
+					"verticalContentAlignment": "Center",
+					"items": []map[string]interface{}{
+						{
+							"type":   "TextBlock",
+							"text":   repo,
+							"weight": "Bolder",
+							"wrap":   true,
+						},
+					},
+				},
+			},
+		},
+	}
+	// rendered message replaces both title and body.
+	if info.Message != "" {
+		body = append(body, map[string]interface{}{
+			"type": "TextBlock",
+			"text": info.Message,
+			"wrap": true,
+		})
+	} else {
+		body = append(body, map[string]interface{}{
+			"type":   "TextBlock",
+			"text":   fmt.Sprintf("new %s: %s", info.Target, info.Title),
+			"size":   "Medium",
+			"weight": "Bolder",
+			"color":  "Accent",
+			"wrap":   true,
+		})
+	}
+	if info.Body != "" && info.Message == "" {
+		body = append(body, map[string]interface{}{
+			"type": "TextBlock",
+			"text": info.Body,
+			"wrap": true,
+		})
+	}
+	facts := []map[string]string{
+		{"title": "target", "value": info.Target},
+		{"title": "current", "value": info.Current},
+	}
+	if info.Prev != "" {
+		facts = append(facts, map[string]string{"title": "prev", "value": info.Prev})
+	}
+	body = append(body, map[string]interface{}{
+		"type":  "FactSet",
+		"facts": facts,
+	})
+
+	var actions []map[string]interface{}
+	if info.Link != "" {
+		actions = append(actions, map[string]interface{}{
+			"type":  "Action.OpenUrl",
+			"title": "Open",
+			"url":   info.Link,
+		})
+	}
+	return n.post(teamsCard(body, actions))
+}
+
+// Error notifies error to teams.
+func (n *TeamsNotifier) Error(err error) error {
+	return n.post(teamsCard([]map[string]interface{}{
+		{
+			"type":   "TextBlock",
+			"text":   "failed",
+			"size":   "Medium",
+			"weight": "Bolder",
+			"color":  "Attention",
+		},
+		{
+			"type": "TextBlock",
+			"text": err.Error(),
+			"wrap": true,
+		},
+	}, nil))
+}
+
+// teamsCard wraps adaptive card elements into message teams accepts.
+func teamsCard(body []map[string]interface{}, actions []map[string]interface{}) map[string]interface{} {
+	content := map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    body,
+		"msteams": map[string]string{"width": "Full"},
+	}
+	if len(actions) > 0 {
+		content["actions"] = actions
+	}
+
+	return map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{
+			{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content":     content,
+			},
+		},
+	}
+}
+
+func (n *TeamsNotifier) post(payload map[string]interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	res, err := http.Post(n.WebhookURL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// incoming webhook responds 200, and workflows responds 202.
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("failed to notify")
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package watchcat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DefaultTelegramAPIURL is the url of telegram bot api.
+const DefaultTelegramAPIURL = "https://api.telegram.org"
+
+// telegramMessageLimit is the max length of a telegram message.
+const telegramMessageLimit = 4096
+
+// telegramEscaper escapes characters reserved by MarkdownV2.
+var telegramEscaper = strings.NewReplacer(
+	`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`,
+	"~", `\~`, "`", "\\`", ">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`,
+	"|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+)
+
+// telegramURLEscaper escapes characters reserved inside link url of MarkdownV2.
+var telegramURLEscaper = strings.NewReplacer(`\`, `\\`, ")", `\)`)
+
+// TelegramNotifier handles notifications to telegram chat via bot.
+type TelegramNotifier struct {
+	Token  string `toml:"token"`
+	ChatID string `toml:"chat_id"`
+	// APIURL overrides url of bot api. default is https://api.telegram.org.
+	APIURL string `toml:"api_url"`
+}
+
+// Notify notifies to telegram.
+func (n *TelegramNotifier) Notify(info *NotificationInfo) error {
+	header := fmt.Sprintf("*%s* new %s: %s",
+		telegramLink(fmt.Sprintf("%s/%s", info.Owner, info.RepoName), info.RepoURL),
+		telegramEscaper.Replace(info.Target),
+		telegramLink(info.Title, info.Link),
+	)
+	body := info.Body
+	// rendered message is sent as plain text under the repository.
+	if info.Message != "" {
+		header = fmt.Sprintf("*%s*", telegramLink(fmt.Sprintf("%s/%s", info.Owner, info.RepoName), info.RepoURL))
+		body = info.Message
+	}
+
+	for _, text := range splitTelegramMessage(header, body) {
+		if err := n.send(text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Error notifies error to telegram.
+func (n *TelegramNotifier) Error(err error) error {
+	for _, text := range splitTelegramMessage("*failed*", err.Error()) {
+		if err := n.send(text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *TelegramNotifier) send(text string) error {
+	data, err := json.Marshal(map[string]interface{}{
+		"chat_id":                  n.ChatID,
+		"text":                     text,
+		"parse_mode":               "MarkdownV2",
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return err
+	}
+
+	apiURL := n.APIURL
+	if apiURL == "" {
+		apiURL = DefaultTelegramAPIURL
+	}
+	u := fmt.Sprintf("%s/bot%s/sendMessage", strings.TrimSuffix(apiURL, "/"), n.Token)
+	res, err := http.Post(u, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var body struct {
+			Description string `json:"description"`
+		}
+		json.NewDecoder(res.Body).Decode(&body)
+		return fmt.Errorf("failed to notify to telegram: %s %s", res.Status, body.Description)
+	}
+
+	return nil
+}
+
+// telegramLink formats link of MarkdownV2, or escaped text if url is empty.
+func telegramLink(text string, url string) string {
+	if url == "" {
+		return telegramEscaper.Replace(text)
+	}
+	return fmt.Sprintf("[%s](%s)", telegramEscaper.Replace(text), telegramURLEscaper.Replace(url))
+}
+
+// splitTelegramMessage builds messages of escaped header and body within the limit.
+// body is split before escaping, so that escape sequences are never split.
+func splitTelegramMessage(header string, body string) []string {
+	if body == "" {
+		return []string{header}
+	}
+
+	var messages []string
+	current := header + "\n\n"
+	size := len([]rune(current))
+	var chunk []rune
+	flush := func() {
+		messages = append(messages, current+telegramEscaper.Replace(string(chunk)))
+		current = ""
+		size = 0
+		chunk = nil
+	}
+
+	for _, r := range body {
+		escaped := len([]rune(telegramEscaper.Replace(string(r))))
+		if size+escaped > telegramMessageLimit {
+			// prefer breaking at the last newline.
+			if i := lastRuneIndex(chunk, '\n'); i > len(chunk)/2 {
+				rest := append([]rune{}, chunk[i+1:]...)
+				chunk = chunk[:i]
+				flush()
+				chunk = rest
+				size = len([]rune(telegramEscaper.Replace(string(rest))))
+			} else {
+				flush()
+			}
+		}
+		chunk = append(chunk, r)
+		size += escaped
+	}
+	flush()
+
+	return messages
+}
+
+func lastRuneIndex(rs []rune, r rune) int {
+	for i := len(rs) - 1; i >= 0; i-- {
+		if rs[i] == r {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,132 @@
+package watchcat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeTelegram records messages sent to the bot api, and fails with status if it is set.
+type fakeTelegram struct {
+	paths    []string
+	messages []map[string]interface{}
+	status   int
+}
+
+func (f *fakeTelegram) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.paths = append(f.paths, r.URL.Path)
+	var msg map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&msg)
+	f.messages = append(f.messages, msg)
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		fmt.Fprint(w, `{"ok":false,"description":"Bad Request: chat not found"}`)
+		return
+	}
+	fmt.Fprint(w, `{"ok":true}`)
+}
+
+func TestTelegramNotifierNotify(t *testing.T) {
+	fake := &fakeTelegram{}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	n := &TelegramNotifier{Token: "123:abc", ChatID: "@watchcat", APIURL: srv.URL + "/"}
+	err := n.Notify(&NotificationInfo{
+		Owner:    "golang",
+		RepoName: "go",
+		RepoURL:  "https://github.com/golang/go",
+		Target:   TargetRelease,
+		Title:    "go1.12",
+		Link:     "https://github.com/golang/go/releases/tag/go1.12",
+		Body:     "see https://golang.org/doc/go1.12 (release notes)!",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.messages) != 1 || fake.paths[0] != "/bot123:abc/sendMessage" {
+		t.Fatalf("unexpected requests: %q", fake.paths)
+	}
+	msg := fake.messages[0]
+	if msg["chat_id"] != "@watchcat" || msg["parse_mode"] != "MarkdownV2" {
+		t.Errorf("unexpected message: %v", msg)
+	}
+	want := "*[golang/go](https://github.com/golang/go)* new release: [go1\\.12](https://github.com/golang/go/releases/tag/go1.12)\n\n" +
+		"see https://golang\\.org/doc/go1\\.12 \\(release notes\\)\\!"
+	if msg["text"] != want {
+		t.Errorf("text = %q, want %q", msg["text"], want)
+	}
+}
+
+func TestTelegramNotifierError(t *testing.T) {
+	fake := &fakeTelegram{status: http.StatusBadRequest}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	n := &TelegramNotifier{Token: "123:abc", ChatID: "1", APIURL: srv.URL}
+	err := n.Error(errors.New("rate limited"))
+	if err == nil || !strings.Contains(err.Error(), "chat not found") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fake.messages) != 1 || fake.messages[0]["text"] != "*failed*\n\nrate limited" {
+		t.Errorf("unexpected messages: %v", fake.messages)
+	}
+}
+
+func TestTelegramLink(t *testing.T) {
+	cases := []struct {
+		text string
+		url  string
+		want string
+	}{
+		{"golang/go", "", "golang/go"},
+		{"fix_bug [v1.0]", "", `fix\_bug \[v1\.0\]`},
+		{"go1.12", "https://example.com/a_(b)", `[go1\.12](https://example.com/a_(b\))`},
+	}
+	for _, c := range cases {
+		if got := telegramLink(c.text, c.url); got != c.want {
+			t.Errorf("telegramLink(%q, %q) = %q, want %q", c.text, c.url, got, c.want)
+		}
+	}
+}
+
+func TestSplitTelegramMessage(t *testing.T) {
+	cases := []struct {
+		name     string
+		body     string
+		messages int
+	}{
+		{"empty", "", 1},
+		{"short", "fixes a bug.", 1},
+		{"lines", strings.Repeat("fixes a bug.\n", 700), 3},
+		{"escaped", strings.Repeat(".", telegramMessageLimit), 3},
+		{"multibyte", strings.Repeat("修正", telegramMessageLimit/2), 2},
+	}
+	for _, c := range cases {
+		messages := splitTelegramMessage("*header*", c.body)
+		if len(messages) != c.messages {
+			t.Errorf("%s: %d messages, want %d", c.name, len(messages), c.messages)
+		}
+		var joined string
+		for _, m := range messages {
+			if n := len([]rune(m)); n > telegramMessageLimit {
+				t.Errorf("%s: message of %d runes exceeds the limit", c.name, n)
+			}
+			if strings.HasSuffix(m, `\`) && !strings.HasSuffix(m, `\\`) {
+				t.Errorf("%s: escape sequence is split", c.name)
+			}
+			joined += m
+		}
+		if !strings.HasPrefix(messages[0], "*header*") {
+			t.Errorf("%s: no header", c.name)
+		}
+		if got := strings.Replace(joined, "\n", "", -1); got != strings.Replace("*header*"+telegramEscaper.Replace(c.body), "\n", "", -1) {
+			t.Errorf("%s: body is lost", c.name)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package watchcat
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// event types of webhook payload.
+const (
+	EventNotification = "notification"
+	EventError        = "error"
+)
+
+// defaultWebhookTimeout is used when WebhookNotifier.Timeout is not specified.
+const defaultWebhookTimeout = 20 * time.Second
+
+// WebhookNotifier handles notifications to arbitrary url as json.
+type WebhookNotifier struct {
+	URL string `toml:"url"`
+	// Headers are added to every request.
+	Headers map[string]string `toml:"headers"`
+	// Secret signs body with HMAC-SHA256 into X-Watchcat-Signature header, if it is specified.
+	Secret  string        `toml:"secret"`
+	Timeout time.Duration `toml:"-"`
+}
+
+// WebhookPayload is the json body WebhookNotifier posts.
+type WebhookPayload struct {
+	// Event is "notification" or "error".
+	Event        string            `json:"event"`
+	SentAt       time.Time         `json:"sent_at"`
+	Notification *NotificationInfo `json:"notification,omitempty"`
+	Error        string            `json:"error,omitempty"`
+}
+
+// Notify posts notification to the url.
+func (n *WebhookNotifier) Notify(info *NotificationInfo) error {
+	return n.post(&WebhookPayload{
+		Event:        EventNotification,
+		SentAt:       time.Now(),
+		Notification: info,
+	})
+}
+
+// Error posts error to the url.
+func (n *WebhookNotifier) Error(err error) error {
+	return n.post(&WebhookPayload{
+		Event:  EventError,
+		SentAt: time.Now(),
+		Error:  err.Error(),
+	})
+}
+
+func (n *WebhookNotifier) post(payload *WebhookPayload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, n.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "watchcat")
+	req.Header.Set("X-Watchcat-Event", payload.Event)
+	for k, v := range n.Headers {
+		req.Header.Set(k, v)
+	}
+	if n.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(n.Secret))
+		mac.Write(data)
+		req.Header.Set("X-Watchcat-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	timeout := n.Timeout
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+	client := http.Client{Timeout: timeout}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("failed to notify to webhook: %s", res.Status)
+	}
+
+	return nil
+}
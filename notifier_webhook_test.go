@@ -0,0 +1,75 @@
+package watchcat
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookNotifierSignature(t *testing.T) {
+	var body []byte
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		header = r.Header
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		name   string
+		secret string
+		notify func(n *WebhookNotifier) error
+		event  string
+	}{
+		{"notification", "s3cr3t", func(n *WebhookNotifier) error { return n.Notify(&NotificationInfo{Owner: "golang", RepoName: "go"}) }, EventNotification},
+		{"error", "s3cr3t", func(n *WebhookNotifier) error { return n.Error(errors.New("failed")) }, EventError},
+		{"unsigned", "", func(n *WebhookNotifier) error { return n.Notify(&NotificationInfo{}) }, EventNotification},
+	}
+	for _, c := range cases {
+		n := &WebhookNotifier{URL: srv.URL, Secret: c.secret, Headers: map[string]string{"X-Team": "a"}}
+		if err := c.notify(n); err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+
+		want := ""
+		if c.secret != "" {
+			mac := hmac.New(sha256.New, []byte(c.secret))
+			mac.Write(body)
+			want = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		}
+		if got := header.Get("X-Watchcat-Signature"); got != want {
+			t.Errorf("%s: signature = %q, want %q", c.name, got, want)
+		}
+		if header.Get("X-Watchcat-Event") != c.event || header.Get("X-Team") != "a" || header.Get("Content-Type") != "application/json" {
+			t.Errorf("%s: unexpected headers: %v", c.name, header)
+		}
+	}
+
+}
+
+func TestWebhookNotifierStatus(t *testing.T) {
+	cases := []struct {
+		code int
+		fail bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusNoContent, false},
+		{http.StatusMovedPermanently, true},
+		{http.StatusInternalServerError, true},
+	}
+	for _, c := range cases {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(c.code)
+		}))
+		err := (&WebhookNotifier{URL: srv.URL}).Notify(&NotificationInfo{})
+		srv.Close()
+		if (err != nil) != c.fail {
+			t.Errorf("%d: unexpected error: %v", c.code, err)
+		}
+	}
+}
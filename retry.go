@@ -0,0 +1,222 @@
+package watchcat
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kudohamu/watchcat/internal/lmdb"
+)
+
+// defaultMaxRetries is used when Option.MaxRetries is not specified.
+const defaultMaxRetries = 8
+
+// retryBackoff is the delay of the first retry, which is doubled on every failure.
+const retryBackoff = 1 * time.Minute
+
+// maxRetryBackoff caps the delay of retries.
+const maxRetryBackoff = 6 * time.Hour
+
+// maxDeliveries caps failed deliveries kept in the queue.
+const maxDeliveries = 1000
+
+const bktRetry = "retry"
+
+// delivery is the notification failed to be delivered.
+type delivery struct {
+	ID        string            `json:"id"`
+	Notifier  string            `json:"notifier"`
+	Info      *NotificationInfo `json:"info"`
+	Attempts  int               `json:"attempts"`
+	NextAt    time.Time         `json:"next_at"`
+	LastError string            `json:"last_error"`
+}
+
+// retryQueue persists failed deliveries in the store to retry them later.
+type retryQueue struct {
+	mu         sync.Mutex
+	store      lmdb.Store
+	maxRetries int
+}
+
+// retryNotifier queues notifications which Notifier failed to deliver.
+type retryNotifier struct {
+	Notifier
+	name  string
+	queue *retryQueue
+}
+
+// Notify notifies, and queues notification to retry if it failed.
+// the failure is still returned, so that it is reported to the other notifiers at once.
+func (n *retryNotifier) Notify(info *NotificationInfo) error {
+	err := n.Notifier.Notify(info)
+	if err == nil {
+		return nil
+	}
+	qerr := n.queue.push(&delivery{
+		ID:        deliveryID(),
+		Notifier:  n.name,
+		Info:      info,
+		Attempts:  1,
+		NextAt:    time.Now().Add(retryBackoff),
+		LastError: err.Error(),
+	})
+	if qerr != nil {
+		return fmt.Errorf("%s (failed to queue to retry: %s)", err, qerr)
+	}
+	return fmt.Errorf("%s (will be retried)", err)
+}
+
+// deliveryID returns random id of delivery, which never collides with deliveries pushed at the same time.
+func deliveryID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (q *retryQueue) push(d *delivery) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	ds, err := q.read()
+	if err != nil {
+		return err
+	}
+	ds = append(ds, d)
+	var dropped []*delivery
+	if len(ds) > maxDeliveries {
+		dropped = ds[:len(ds)-maxDeliveries]
+		ds = ds[len(ds)-maxDeliveries:]
+	}
+	if err := q.write(ds); err != nil {
+		return err
+	}
+	if len(dropped) > 0 {
+		return fmt.Errorf("retry queue is full, dropped %d notifications", len(dropped))
+	}
+	return nil
+}
+
+// retry redelivers queued notifications whose time has come with notifiers keyed by name.
+// it returns errors of notifications given up.
+func (q *retryQueue) retry(ns map[string]Notifier) []error {
+	q.mu.Lock()
+	ds, err := q.read()
+	q.mu.Unlock()
+	if err != nil {
+		return []error{err}
+	}
+	if len(ds) == 0 {
+		return nil
+	}
+
+	maxRetries := q.maxRetries
+	if maxRetries <= 0 {
+		maxRetries = defaultMaxRetries
+	}
+
+	now := time.Now()
+	done := map[*delivery]bool{}
+	var errs []error
+	for _, d := range ds {
+		if now.Before(d.NextAt) {
+			continue
+		}
+		n, ok := ns[d.Notifier]
+		if !ok {
+			done[d] = true
+			errs = append(errs, fmt.Errorf("(%s/%s) gave up notifying %s to %s: notifier no longer exists", d.Info.Owner, d.Info.RepoName, d.Info.Target, d.Notifier))
+			continue
+		}
+		err := n.Notify(d.Info)
+		if err == nil {
+			done[d] = true
+			continue
+		}
+		d.LastError = err.Error()
+		d.Attempts++
+		if d.Attempts > maxRetries {
+			done[d] = true
+			errs = append(errs, fmt.Errorf("(%s/%s) gave up notifying %s to %s after %d attempts: %s", d.Info.Owner, d.Info.RepoName, d.Info.Target, d.Notifier, d.Attempts, d.LastError))
+			continue
+		}
+		backoff := retryBackoff << uint(d.Attempts-1)
+		if backoff > maxRetryBackoff || backoff <= 0 {
+			backoff = maxRetryBackoff
+		}
+		d.NextAt = now.Add(backoff)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	// deliveries may be pushed while retrying.
+	current, err := q.read()
+	if err != nil {
+		return append(errs, err)
+	}
+	retried := map[string]*delivery{}
+	for _, d := range ds {
+		retried[d.ID] = d
+	}
+	remaining := make([]*delivery, 0, len(current))
+	for _, c := range current {
+		d, ok := retried[c.ID]
+		if !ok {
+			remaining = append(remaining, c)
+			continue
+		}
+		if !done[d] {
+			remaining = append(remaining, d)
+		}
+	}
+	if err := q.write(remaining); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// retryOf wraps notifier named name to queue failed notifications, keeping wrappers across checks.
+func (w *Watcher) retryOf(n Notifier, name string) Notifier {
+	if w.queue == nil {
+		return n
+	}
+
+	w.retryMu.Lock()
+	defer w.retryMu.Unlock()
+	if r, ok := w.retries[n]; ok && r.name == name {
+		return r
+	}
+	r := &retryNotifier{
+		Notifier: n,
+		name:     name,
+		queue:    w.queue,
+	}
+	w.retries[n] = r
+	return r
+}
+
+func (q *retryQueue) read() ([]*delivery, error) {
+	data, err := q.store.Get(bktRetry, "deliveries")
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var ds []*delivery
+	if err := json.Unmarshal(data, &ds); err != nil {
+		return nil, err
+	}
+	return ds, nil
+}
+
+func (q *retryQueue) write(ds []*delivery) error {
+	if len(ds) == 0 {
+		return q.store.Delete(bktRetry, "deliveries")
+	}
+	data, err := json.Marshal(ds)
+	if err != nil {
+		return err
+	}
+	return q.store.Put(bktRetry, "deliveries", data)
+}
@@ -0,0 +1,176 @@
+package watchcat
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kudohamu/watchcat/internal/lmdb"
+)
+
+// failingNotifier fails to notify while fail is true.
+type failingNotifier struct {
+	recordNotifier
+	fail bool
+}
+
+func (n *failingNotifier) Notify(info *NotificationInfo) error {
+	if n.fail {
+		return errors.New("unavailable")
+	}
+	return n.recordNotifier.Notify(info)
+}
+
+// dueAll makes all queued deliveries due.
+func dueAll(t *testing.T, q *retryQueue) {
+	ds, err := q.read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range ds {
+		d.NextAt = time.Now().Add(-time.Second)
+	}
+	if err := q.write(ds); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRetryNotifier(t *testing.T) {
+	q := &retryQueue{store: lmdb.NewMemoryStore(), maxRetries: 3}
+	n := &failingNotifier{fail: true}
+	r := &retryNotifier{Notifier: n, name: "slack", queue: q}
+
+	info := &NotificationInfo{Owner: "o", RepoName: "n", Target: TargetRelease, Current: "v1.0.0"}
+	err := r.Notify(info)
+	if err == nil || !strings.Contains(err.Error(), "unavailable") {
+		t.Fatalf("failure is not returned: %v", err)
+	}
+	ds, err := q.read()
+	if err != nil || len(ds) != 1 {
+		t.Fatalf("%d deliveries queued: %v", len(ds), err)
+	}
+	if d := ds[0]; d.Notifier != "slack" || d.Attempts != 1 || d.Info.Current != "v1.0.0" || time.Until(d.NextAt) <= 0 {
+		t.Errorf("unexpected delivery: %+v", d)
+	}
+
+	// deliveries are not retried before their time.
+	if errs := q.retry(map[string]Notifier{"slack": n}); len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	if ds, _ := q.read(); ds[0].Attempts != 1 {
+		t.Errorf("retried before its time: %d attempts", ds[0].Attempts)
+	}
+
+	// backoff is doubled on every failure.
+	for _, want := range []struct {
+		attempts int
+		backoff  time.Duration
+	}{
+		{2, 2 * retryBackoff},
+		{3, 4 * retryBackoff},
+	} {
+		dueAll(t, q)
+		if errs := q.retry(map[string]Notifier{"slack": n}); len(errs) != 0 {
+			t.Fatal(errs)
+		}
+		ds, _ := q.read()
+		if len(ds) != 1 || ds[0].Attempts != want.attempts {
+			t.Fatalf("unexpected deliveries: %+v", ds)
+		}
+		if backoff := time.Until(ds[0].NextAt); backoff > want.backoff || backoff < want.backoff-time.Minute {
+			t.Errorf("backoff after %d attempts is %s, want %s", want.attempts, backoff, want.backoff)
+		}
+	}
+
+	// gave up after max retries.
+	dueAll(t, q)
+	errs := q.retry(map[string]Notifier{"slack": n})
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "gave up") {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	if ds, _ := q.read(); len(ds) != 0 {
+		t.Errorf("%d deliveries are left", len(ds))
+	}
+}
+
+func TestRetryQueueRetry(t *testing.T) {
+	cases := []struct {
+		name      string
+		notifiers func(n *failingNotifier) map[string]Notifier
+		fail      bool
+		notified  int
+		errors    int
+		left      int
+	}{
+		{"delivered", func(n *failingNotifier) map[string]Notifier { return map[string]Notifier{"slack": n} }, false, 1, 0, 0},
+		{"failed again", func(n *failingNotifier) map[string]Notifier { return map[string]Notifier{"slack": n} }, true, 0, 0, 1},
+		{"notifier removed", func(n *failingNotifier) map[string]Notifier { return map[string]Notifier{} }, false, 0, 1, 0},
+	}
+	for _, c := range cases {
+		q := &retryQueue{store: lmdb.NewMemoryStore()}
+		n := &failingNotifier{fail: true}
+		(&retryNotifier{Notifier: n, name: "slack", queue: q}).Notify(&NotificationInfo{Owner: "o", RepoName: "n", Target: TargetIssue})
+		n.fail = c.fail
+
+		dueAll(t, q)
+		errs := q.retry(c.notifiers(n))
+		ds, _ := q.read()
+		if len(n.infos) != c.notified || len(errs) != c.errors || len(ds) != c.left {
+			t.Errorf("%s: %d notified, errors %v, %d left", c.name, len(n.infos), errs, len(ds))
+		}
+	}
+}
+
+func TestRetryQueuePushFull(t *testing.T) {
+	q := &retryQueue{store: lmdb.NewMemoryStore()}
+	ds := make([]*delivery, 0, maxDeliveries)
+	for i := 0; i < maxDeliveries; i++ {
+		ds = append(ds, &delivery{ID: fmt.Sprint(i), Info: &NotificationInfo{}})
+	}
+	if err := q.write(ds); err != nil {
+		t.Fatal(err)
+	}
+	err := q.push(&delivery{ID: "last", Info: &NotificationInfo{}})
+	if err == nil || !strings.Contains(err.Error(), "dropped 1") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	ds, err = q.read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the oldest delivery is dropped.
+	if len(ds) != maxDeliveries || ds[0].ID != "1" || ds[len(ds)-1].ID != "last" {
+		t.Errorf("unexpected deliveries: %d from %s to %s", len(ds), ds[0].ID, ds[len(ds)-1].ID)
+	}
+}
+
+func TestRetryNotifierConcurrentIDs(t *testing.T) {
+	q := &retryQueue{store: lmdb.NewMemoryStore()}
+	r := &retryNotifier{Notifier: &failingNotifier{fail: true}, name: "slack", queue: q}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.Notify(&NotificationInfo{})
+		}()
+	}
+	wg.Wait()
+
+	ds, err := q.read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := map[string]bool{}
+	for _, d := range ds {
+		ids[d.ID] = true
+	}
+	if len(ds) != 20 || len(ids) != 20 {
+		t.Errorf("%d deliveries with %d ids", len(ds), len(ids))
+	}
+}
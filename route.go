@@ -0,0 +1,137 @@
+package watchcat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// definedNotifier is the notifier defined in config.
+// it is kept while its definition is unchanged, so that its state survives reloading config.
+type definedNotifier struct {
+	definition string
+	notifier   Notifier
+}
+
+// notifierDefinition is the common part of notifier definitions in config.
+type notifierDefinition struct {
+	Type string `toml:"type"`
+	// Timeout is duration like "30s" for webhook and exec.
+	Timeout string `toml:"timeout"`
+}
+
+// defineNotifiers builds notifiers defined in config, keyed by their names.
+func (w *Watcher) defineNotifiers(config *Config) (map[string]Notifier, error) {
+	defined := map[string]*definedNotifier{}
+	ns := map[string]Notifier{}
+	var errs []string
+	for name, prim := range config.Notifiers {
+		// names of notifier types refer to notifiers of command line in routes and the retry queue.
+		if _, err := newNotifier(name); err == nil {
+			errs = append(errs, fmt.Sprintf("%s: name of notifier type can't be used", name))
+			continue
+		}
+		n, err := decodeNotifier(&config.md, prim)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", name, err))
+			continue
+		}
+		data, err := json.Marshal(n)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", name, err))
+			continue
+		}
+		definition := fmt.Sprintf("%T%s", n, data)
+		if d, ok := w.defined[name]; ok && d.definition == definition {
+			n = d.notifier
+		}
+		defined[name] = &definedNotifier{
+			definition: definition,
+			notifier:   n,
+		}
+
+		wrapped, err := w.wrap(n, name, config.Templates)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		ns[name] = wrapped
+	}
+	w.defined = defined
+
+	if len(errs) > 0 {
+		return ns, fmt.Errorf("invalid notifiers: %s", strings.Join(errs, ", "))
+	}
+	return ns, nil
+}
+
+func decodeNotifier(md *toml.MetaData, prim toml.Primitive) (Notifier, error) {
+	var def notifierDefinition
+	if err := md.PrimitiveDecode(prim, &def); err != nil {
+		return nil, err
+	}
+	// feeds are served by Watcher only for notifiers of command line.
+	if def.Type == "feed" {
+		return nil, fmt.Errorf("feed notifier is available only from command line")
+	}
+	n, err := newNotifier(def.Type)
+	if err != nil {
+		return nil, err
+	}
+	if err := md.PrimitiveDecode(prim, n); err != nil {
+		return nil, err
+	}
+
+	if def.Timeout != "" {
+		timeout, err := time.ParseDuration(def.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout: %s", def.Timeout)
+		}
+		switch n := n.(type) {
+		case *WebhookNotifier:
+			n.Timeout = timeout
+		case *ExecNotifier:
+			n.Timeout = timeout
+		}
+	}
+	return n, nil
+}
+
+// routeNotifiers returns notifiers receiving events of target of repo.
+// events of repo without routes are notified to defaults, which are notifiers of command line.
+func routeNotifiers(repo *RepoConfig, target string, defaults notifiers, defined map[string]Notifier) (notifiers, error) {
+	names := repo.Routes[target]
+	if len(names) == 0 {
+		names = repo.Notifiers
+	}
+	if len(names) == 0 {
+		return defaults, nil
+	}
+
+	ns := notifiers{}
+	var unknown []string
+	for _, name := range names {
+		if n, ok := defined[name]; ok {
+			ns = append(ns, n)
+			continue
+		}
+		// notifiers of command line are also routed by their names.
+		found := false
+		for _, n := range defaults {
+			if notifierName(n) == name {
+				ns = append(ns, n)
+				found = true
+			}
+		}
+		if !found {
+			unknown = append(unknown, name)
+		}
+	}
+
+	if len(unknown) > 0 {
+		return ns, fmt.Errorf("(%s/%s) unknown notifiers of %s: %s", repo.Owner, repo.Name, target, strings.Join(unknown, ", "))
+	}
+	return ns, nil
+}
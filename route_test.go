@@ -0,0 +1,137 @@
+package watchcat
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// decodeConfig decodes config written in toml.
+func decodeConfig(t *testing.T, data string) *Config {
+	var config Config
+	md, err := toml.Decode(data, &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.md = md
+	return &config
+}
+
+func TestDefineNotifiersReservedNames(t *testing.T) {
+	config := decodeConfig(t, `
+[notifiers.slack]
+  type = "slack"
+  webhook_url = "https://hooks.slack.com/services/XXX"
+[notifiers.releases]
+  type = "slack"
+  webhook_url = "https://hooks.slack.com/services/YYY"
+`)
+	w := &Watcher{}
+	ns, err := w.defineNotifiers(config)
+	if err == nil || !strings.Contains(err.Error(), "slack") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, ok := ns["slack"]; ok {
+		t.Error("notifier named slack is defined")
+	}
+	if _, ok := ns["releases"]; !ok {
+		t.Error("notifier named releases is not defined")
+	}
+}
+
+func TestDefineNotifiers(t *testing.T) {
+	config := decodeConfig(t, `
+[notifiers.releases]
+  type = "webhook"
+  url = "https://example.com/hook"
+  timeout = "5s"
+[notifiers.ops]
+  type = "slack"
+  webhook_url = "https://hooks.slack.com/services/XXX"
+[notifiers.untyped]
+  url = "https://example.com/hook"
+[notifiers.feeds]
+  type = "feed"
+[notifiers.slow]
+  type = "exec"
+  command = ["true"]
+  timeout = "soon"
+`)
+	w := &Watcher{}
+	ns, err := w.defineNotifiers(config)
+	for _, name := range []string{"untyped", "feeds", "slow"} {
+		if err == nil || !strings.Contains(err.Error(), name+":") {
+			t.Errorf("%s is not reported: %v", name, err)
+		}
+		if _, ok := ns[name]; ok {
+			t.Errorf("invalid notifier %s is defined", name)
+		}
+	}
+	if n, ok := ns["releases"].(*WebhookNotifier); !ok || n.URL != "https://example.com/hook" || n.Timeout != 5*time.Second {
+		t.Errorf("unexpected releases: %#v", ns["releases"])
+	}
+	ops := ns["ops"]
+	if n, ok := ops.(*SlackNotifier); !ok || n.WebhookURL != "https://hooks.slack.com/services/XXX" {
+		t.Errorf("unexpected ops: %#v", ops)
+	}
+
+	// notifiers are kept across reloads unless their definitions change.
+	ns, _ = w.defineNotifiers(config)
+	if ns["ops"] != ops {
+		t.Error("unchanged notifier is recreated")
+	}
+	config = decodeConfig(t, `
+[notifiers.ops]
+  type = "slack"
+  webhook_url = "https://hooks.slack.com/services/YYY"
+`)
+	ns, _ = w.defineNotifiers(config)
+	if ns["ops"] == ops {
+		t.Error("changed notifier is kept")
+	}
+}
+
+func TestRouteNotifiers(t *testing.T) {
+	std := &StdNotifier{}
+	slack := &SlackNotifier{}
+	releases := &WebhookNotifier{}
+	ops := &recordNotifier{}
+	defaults := notifiers{std, slack}
+	defined := map[string]Notifier{"releases": releases, "ops": ops}
+
+	cases := []struct {
+		name   string
+		repo   *RepoConfig
+		target string
+		want   notifiers
+		fail   bool
+	}{
+		{"command line", &RepoConfig{}, TargetRelease, defaults, false},
+		{"repository", &RepoConfig{Notifiers: []string{"ops"}}, TargetRelease, notifiers{ops}, false},
+		{"route", &RepoConfig{Notifiers: []string{"ops"}, Routes: map[string][]string{TargetRelease: {"releases", "ops"}}}, TargetRelease, notifiers{releases, ops}, false},
+		{"other route", &RepoConfig{Notifiers: []string{"ops"}, Routes: map[string][]string{TargetRelease: {"releases"}}}, TargetIssue, notifiers{ops}, false},
+		{"command line by name", &RepoConfig{Notifiers: []string{"slack", "ops"}}, TargetIssue, notifiers{slack, ops}, false},
+		{"unknown", &RepoConfig{Notifiers: []string{"ops", "email", "nowhere"}}, TargetIssue, notifiers{ops}, true},
+	}
+	for _, c := range cases {
+		c.repo.Owner, c.repo.Name = "golang", "go"
+		got, err := routeNotifiers(c.repo, c.target, defaults, defined)
+		if (err != nil) != c.fail {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+		}
+		if c.fail && !strings.Contains(err.Error(), "email, nowhere") {
+			t.Errorf("%s: unknown notifiers are not reported: %v", c.name, err)
+		}
+		if len(got) != len(c.want) {
+			t.Errorf("%s: %d notifiers, want %d", c.name, len(got), len(c.want))
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("%s: notifier %d is %#v, want %#v", c.name, i, got[i], c.want[i])
+			}
+		}
+	}
+}
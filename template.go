@@ -0,0 +1,156 @@
+package watchcat
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// defaultTemplate is the key of template used for targets without their own template.
+// digests are rendered only with their own template of target "digest".
+const defaultTemplate = "default"
+
+// templateFuncs are helper functions available in message templates.
+var templateFuncs = template.FuncMap{
+	"truncate": func(n int, s string) string { return truncate(s, n) },
+	"slack":    markdownToSlack,
+	"shortsha": shortSHA,
+	"reltime":  relativeTime,
+}
+
+// templateNotifier renders message of notification with template before passing it to Notifier.
+type templateNotifier struct {
+	Notifier
+	// templates are keyed by target, or "default".
+	templates map[string]*template.Template
+}
+
+// Notify renders message and notifies.
+func (n *templateNotifier) Notify(info *NotificationInfo) error {
+	tmpl, ok := n.templates[info.Target]
+	if !ok && info.Target != TargetDigest {
+		tmpl, ok = n.templates[defaultTemplate]
+	}
+	if !ok {
+		return n.Notifier.Notify(info)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, info); err != nil {
+		return fmt.Errorf("failed to render template of %s: %s", info.Target, err)
+	}
+	rendered := *info
+	rendered.Message = buf.String()
+	return n.Notifier.Notify(&rendered)
+}
+
+// withTemplate wraps notifier named name if it has templates in config.
+// config is keyed by notifier name and then by target.
+// templates of the type of notifier are used if there is none for the name.
+func withTemplate(n Notifier, name string, config map[string]map[string]string) (Notifier, error) {
+	texts := config[name]
+	if len(texts) == 0 {
+		texts = config[notifierName(n)]
+	}
+	if len(texts) == 0 {
+		return n, nil
+	}
+
+	templates := map[string]*template.Template{}
+	for target, text := range texts {
+		tmpl, err := template.New(name + "." + target).Funcs(templateFuncs).Parse(text)
+		if err != nil {
+			return n, fmt.Errorf("invalid template of %s: %s", name, err)
+		}
+		templates[target] = tmpl
+	}
+	return &templateNotifier{
+		Notifier:  n,
+		templates: templates,
+	}, nil
+}
+
+var (
+	mdCodeBlock = regexp.MustCompile("(?s)```[a-zA-Z0-9_+-]*\n(.*?)```")
+	mdHeading   = regexp.MustCompile(`(?m)^#{1,6}\s+(.+?)\s*#*$`)
+	mdImage     = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)[^)]*\)`)
+	mdLink      = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)[^)]*\)`)
+	mdBold      = regexp.MustCompile(`(\*\*|__)(\S(?:.*?\S)?)(\*\*|__)`)
+	mdItalic    = regexp.MustCompile(`(^|[^*\w])\*([^*\s](?:[^*]*?[^*\s])?)\*`)
+	mdStrike    = regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`)
+	mdListItem  = regexp.MustCompile(`(?m)^(\s*)[*+-]\s+`)
+)
+
+// markdownToSlack converts github flavored markdown to slack's mrkdwn roughly.
+func markdownToSlack(md string) string {
+	// protect code blocks from conversion.
+	var blocks []string
+	s := mdCodeBlock.ReplaceAllStringFunc(md, func(b string) string {
+		blocks = append(blocks, "```\n"+mdCodeBlock.FindStringSubmatch(b)[1]+"```")
+		return fmt.Sprintf("\x00%d\x00", len(blocks)-1)
+	})
+
+	s = strings.Replace(s, "\r\n", "\n", -1)
+	s = mdListItem.ReplaceAllString(s, "$1• ")
+	s = mdImage.ReplaceAllString(s, "<$2|$1>")
+	s = mdLink.ReplaceAllString(s, "<$2|$1>")
+	s = mdItalic.ReplaceAllString(s, "${1}_${2}_")
+	// bold is converted to "*" after italic, so that it's never taken as italic.
+	s = mdHeading.ReplaceAllString(s, "**$1**")
+	s = mdBold.ReplaceAllString(s, "*$2*")
+	s = mdStrike.ReplaceAllString(s, "~$1~")
+
+	for i, b := range blocks {
+		s = strings.Replace(s, fmt.Sprintf("\x00%d\x00", i), b, 1)
+	}
+	return s
+}
+
+// shortSHA shortens commit hash to 7 characters.
+func shortSHA(sha string) string {
+	if len(sha) <= 7 {
+		return sha
+	}
+	return sha[:7]
+}
+
+// relativeTime describes t relative to now, like "3 hours ago".
+func relativeTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	d := time.Since(t)
+	format := "%s ago"
+	if d < 0 {
+		d = -d
+		format = "in %s"
+	}
+
+	var s string
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		s = plural(int(d/time.Minute), "minute")
+	case d < 24*time.Hour:
+		s = plural(int(d/time.Hour), "hour")
+	case d < 30*24*time.Hour:
+		s = plural(int(d/(24*time.Hour)), "day")
+	case d < 365*24*time.Hour:
+		s = plural(int(d/(30*24*time.Hour)), "month")
+	default:
+		s = plural(int(d/(365*24*time.Hour)), "year")
+	}
+	return fmt.Sprintf(format, s)
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
@@ -0,0 +1,138 @@
+package watchcat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithTemplate(t *testing.T) {
+	config := map[string]map[string]string{
+		"chat": {
+			"default":     "{{.Owner}}/{{.RepoName}} {{.Target}} {{.Title}}",
+			TargetRelease: "released {{.Current}} after {{.Prev}}",
+			TargetCommit:  "{{shortsha .Current}} {{truncate 10 .Extra.subject}}",
+		},
+		"md": {
+			TargetIssue:  "{{slack .Body}}",
+			TargetDigest: "{{.Extra.events}} events",
+		},
+	}
+	info := func(target string) *NotificationInfo {
+		return &NotificationInfo{
+			Owner:    "golang",
+			RepoName: "go",
+			Target:   target,
+			Current:  "0123456789abcdef",
+			Prev:     "go1.11",
+			Title:    "go1.12",
+			Body:     "**fixed** [issue](https://github.com/golang/go/issues/1)",
+			Extra:    map[string]string{"subject": "runtime: fix race of timers", "events": "3"},
+		}
+	}
+	cases := []struct {
+		name    string
+		info    *NotificationInfo
+		message string
+	}{
+		{"chat", info(TargetRelease), "released 0123456789abcdef after go1.11"},
+		{"chat", info(TargetCommit), "0123456 runtime: …"},
+		{"chat", info(TargetTag), "golang/go tag go1.12"},
+		{"chat", info(TargetDigest), ""},
+		{"md", info(TargetIssue), "*fixed* <https://github.com/golang/go/issues/1|issue>"},
+		{"md", info(TargetDigest), "3 events"},
+		{"md", info(TargetRelease), ""},
+	}
+	for _, c := range cases {
+		n := &recordNotifier{}
+		tn, err := withTemplate(n, c.name, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tn.Notify(c.info); err != nil {
+			t.Fatal(err)
+		}
+		if got := n.infos[0].Message; got != c.message {
+			t.Errorf("%s %s: message = %q, want %q", c.name, c.info.Target, got, c.message)
+		}
+		if c.info.Message != "" {
+			t.Errorf("%s %s: notification is modified", c.name, c.info.Target)
+		}
+	}
+}
+
+func TestWithTemplateLookup(t *testing.T) {
+	slack := &SlackNotifier{}
+	config := map[string]map[string]string{
+		"slack": {"default": "{{.Title}}"},
+		"ops":   {"default": "{{.Owner}}"},
+		"bad":   {"default": "{{.Title"},
+	}
+	cases := []struct {
+		name    string
+		wrapped bool
+		fail    bool
+	}{
+		// templates of the type are used if there is none for the name.
+		{"alerts", true, false},
+		{"ops", true, false},
+		{"bad", false, true},
+	}
+	for _, c := range cases {
+		n, err := withTemplate(slack, c.name, config)
+		if (err != nil) != c.fail {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+		}
+		tn, wrapped := n.(*templateNotifier)
+		if wrapped != c.wrapped || (wrapped && tn.Notifier != slack) {
+			t.Errorf("%s: unexpected notifier %#v", c.name, n)
+		}
+	}
+
+	n, _ := withTemplate(&recordNotifier{}, "chat", config)
+	if _, ok := n.(*recordNotifier); !ok {
+		t.Error("notifier without templates is wrapped")
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	cases := []struct {
+		d    time.Duration
+		want string
+	}{
+		{10 * time.Second, "just now"},
+		{time.Minute + time.Second, "1 minute ago"},
+		{3*time.Hour + time.Second, "3 hours ago"},
+		{-(2*24*time.Hour + time.Minute), "in 2 days"},
+		{61 * 24 * time.Hour, "2 months ago"},
+		{400 * 24 * time.Hour, "1 year ago"},
+	}
+	for _, c := range cases {
+		if got := relativeTime(time.Now().Add(-c.d)); got != c.want {
+			t.Errorf("relativeTime(now - %s) = %q, want %q", c.d, got, c.want)
+		}
+	}
+	if relativeTime(time.Time{}) != "" {
+		t.Error("zero time is described")
+	}
+}
+
+func TestMarkdownToSlack(t *testing.T) {
+	cases := []struct {
+		md   string
+		want string
+	}{
+		{"**bold** and *italic*", "*bold* and _italic_"},
+		{"## Changes", "*Changes*"},
+		{"- one\n  * two", "• one\n  • two"},
+		{"~~old~~ ![logo](https://example.com/a.png)", "~old~ <https://example.com/a.png|logo>"},
+		{"```go\n**kept**\n```", "```\n**kept**\n```"},
+	}
+	for _, c := range cases {
+		if got := markdownToSlack(c.md); got != c.want {
+			t.Errorf("markdownToSlack(%q) = %q, want %q", c.md, got, c.want)
+		}
+	}
+	if shortSHA("0123456789abcdef") != "0123456" || shortSHA("abc") != "abc" {
+		t.Error("unexpected short sha")
+	}
+}
@@ -0,0 +1,119 @@
+package watchcat
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	version "github.com/mcuadros/go-version"
+)
+
+// levels of version bump to notify.
+const (
+	BumpMajor = "major"
+	BumpMinor = "minor"
+	BumpPatch = "patch"
+)
+
+var (
+	// versionNumbers finds major, minor and patch numbers in tag name.
+	versionNumbers = regexp.MustCompile(`(\d+)(?:\.(\d+))?(?:\.(\d+))?`)
+	// prereleaseVersion finds suffix of prerelease, like "-rc.1", "beta2" or "nightly".
+	prereleaseVersion = regexp.MustCompile(`(?i)^\D*\d+(\.\d+)*-|(^|[^a-z])(alpha|beta|rc|pre|preview|dev|nightly|snapshot|canary)`)
+	// constraintPart is the form of comma separated parts of version constraint, like ">=1.20", "~1.2" or "1.2.*".
+	constraintPart = regexp.MustCompile(`^(?:[x*]|~\d+(?:\.\d+){0,3}|(?:<>|!=|>=?|<=?|==?)?\s*v?\d+(?:\.\d+){0,3}(?:\.[x*]|-?[0-9A-Za-z][0-9A-Za-z.]*)?)(?:@(?i:stable|rc|beta|alpha|dev))?$`)
+)
+
+// versionFilter selects versions of releases and tags by options of repository.
+type versionFilter struct {
+	constraint *version.ConstraintGroup
+	pattern    *regexp.Regexp
+	prerelease bool
+	draft      bool
+	bump       string
+}
+
+func newVersionFilter(repo *RepoConfig) (*versionFilter, error) {
+	f := &versionFilter{
+		prerelease: repo.Prerelease,
+		draft:      repo.Draft,
+		bump:       repo.Bump,
+	}
+	if repo.Constraint != "" {
+		// go-version never fails to parse, and matches nothing with invalid constraint.
+		for _, part := range strings.Split(repo.Constraint, ",") {
+			if !constraintPart.MatchString(strings.TrimSpace(part)) {
+				return nil, fmt.Errorf("(%s/%s) invalid constraint: %s", repo.Owner, repo.Name, repo.Constraint)
+			}
+		}
+		f.constraint = version.NewConstrainGroupFromString(repo.Constraint)
+	}
+	if repo.TagPattern != "" {
+		p, err := regexp.Compile(repo.TagPattern)
+		if err != nil {
+			return nil, fmt.Errorf("(%s/%s) invalid tag_pattern: %s", repo.Owner, repo.Name, err)
+		}
+		f.pattern = p
+	}
+	switch repo.Bump {
+	case "", BumpMajor, BumpMinor, BumpPatch:
+	default:
+		return nil, fmt.Errorf("(%s/%s) invalid bump: %s", repo.Owner, repo.Name, repo.Bump)
+	}
+	return f, nil
+}
+
+// match reports whether tag is notified.
+// prerelease is the flag of release, or guessed by isPrerelease for tag without release.
+func (f *versionFilter) match(tag string, prerelease bool, draft bool) bool {
+	if draft && !f.draft {
+		return false
+	}
+	if prerelease && !f.prerelease {
+		return false
+	}
+	if f.pattern != nil && !f.pattern.MatchString(tag) {
+		return false
+	}
+	if f.constraint != nil && !f.constraint.Match(trimVersionPrefix(tag)) {
+		return false
+	}
+	return true
+}
+
+// bumped reports whether next bumps prev at the level to notify or higher.
+func (f *versionFilter) bumped(prev string, next string) bool {
+	if f.bump == "" || f.bump == BumpPatch || prev == "" {
+		return true
+	}
+	p := versionNumbers.FindStringSubmatch(prev)
+	n := versionNumbers.FindStringSubmatch(next)
+	if p == nil || n == nil {
+		return true
+	}
+	if numberOf(p[1]) != numberOf(n[1]) {
+		return true
+	}
+	return f.bump == BumpMinor && numberOf(p[2]) != numberOf(n[2])
+}
+
+// isPrerelease guesses whether tag is prerelease from its name.
+func isPrerelease(tag string) bool {
+	return prereleaseVersion.MatchString(tag)
+}
+
+// trimVersionPrefix removes prefix before version number, like "v" or "go".
+func trimVersionPrefix(tag string) string {
+	if i := strings.IndexAny(tag, "0123456789"); i > 0 {
+		return tag[i:]
+	}
+	return tag
+}
+
+func numberOf(s string) string {
+	s = strings.TrimLeft(s, "0")
+	if s == "" {
+		return "0"
+	}
+	return s
+}
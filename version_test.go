@@ -0,0 +1,98 @@
+package watchcat
+
+import "testing"
+
+func TestVersionFilterMatch(t *testing.T) {
+	cases := []struct {
+		repo       *RepoConfig
+		tag        string
+		prerelease bool
+		draft      bool
+		want       bool
+	}{
+		{&RepoConfig{}, "v1.0.0", false, false, true},
+		{&RepoConfig{}, "v1.0.0-rc.1", true, false, false},
+		{&RepoConfig{Prerelease: true}, "v1.0.0-rc.1", true, false, true},
+		{&RepoConfig{}, "v1.0.0", false, true, false},
+		{&RepoConfig{Draft: true}, "v1.0.0", false, true, true},
+		{&RepoConfig{Constraint: ">=1.20, <2"}, "go1.21.0", false, false, true},
+		{&RepoConfig{Constraint: ">=1.20, <2"}, "v1.19.9", false, false, false},
+		{&RepoConfig{Constraint: ">=1.20, <2"}, "v2.0.0", false, false, false},
+		{&RepoConfig{Constraint: "~1.2"}, "1.2.5", false, false, true},
+		{&RepoConfig{TagPattern: `^v\d+\.\d+\.\d+$`}, "v1.2.3", false, false, true},
+		{&RepoConfig{TagPattern: `^v\d+\.\d+\.\d+$`}, "weekly.2019-01-02", false, false, false},
+	}
+	for _, c := range cases {
+		f, err := newVersionFilter(c.repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := f.match(c.tag, c.prerelease, c.draft); got != c.want {
+			t.Errorf("match(%q, %v, %v) with %+v = %v, want %v", c.tag, c.prerelease, c.draft, c.repo, got, c.want)
+		}
+	}
+}
+
+func TestVersionFilterBumped(t *testing.T) {
+	cases := []struct {
+		bump string
+		prev string
+		next string
+		want bool
+	}{
+		{"", "v1.2.3", "v1.2.4", true},
+		{BumpPatch, "v1.2.3", "v1.2.4", true},
+		{BumpMinor, "v1.2.3", "v1.2.4", false},
+		{BumpMinor, "v1.2.3", "v1.3.0", true},
+		{BumpMinor, "v1.2.3", "v2.0.0", true},
+		{BumpMajor, "v1.2.3", "v1.3.0", false},
+		{BumpMajor, "v1.2.3", "v2.0.0", true},
+		{BumpMajor, "go1.11", "go1.12", false},
+		{BumpMinor, "v01.02", "v1.2.1", false},
+		{BumpMajor, "", "v1.0.0", true},
+		{BumpMajor, "latest", "v1.0.0", true},
+	}
+	for _, c := range cases {
+		f, err := newVersionFilter(&RepoConfig{Bump: c.bump})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := f.bumped(c.prev, c.next); got != c.want {
+			t.Errorf("bumped(%q, %q) at %q = %v, want %v", c.prev, c.next, c.bump, got, c.want)
+		}
+	}
+}
+
+func TestNewVersionFilterInvalid(t *testing.T) {
+	cases := []*RepoConfig{
+		{Constraint: "latest"},
+		{Constraint: ">=1.20, foo"},
+		{TagPattern: "("},
+		{Bump: "build"},
+	}
+	for _, c := range cases {
+		if _, err := newVersionFilter(c); err == nil {
+			t.Errorf("no error with %+v", c)
+		}
+	}
+}
+
+func TestIsPrerelease(t *testing.T) {
+	cases := []struct {
+		tag  string
+		want bool
+	}{
+		{"v1.0.0", false},
+		{"go1.12", false},
+		{"v1.0.0-rc.1", true},
+		{"go1.12rc2", true},
+		{"go1.12beta1", true},
+		{"nightly-2019-01-02", true},
+		{"release-1.2", false},
+	}
+	for _, c := range cases {
+		if got := isPrerelease(c.tag); got != c.want {
+			t.Errorf("isPrerelease(%q) = %v, want %v", c.tag, got, c.want)
+		}
+	}
+}
@@ -4,11 +4,13 @@ package watchcat
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -26,8 +28,23 @@ const (
 	TargetIssue   = "issue"
 	TargetPR      = "pr"
 	TargetTag     = "tag"
+	// state changes of pull requests.
+	TargetPRMerged   = "pr_merged"
+	TargetPRClosed   = "pr_closed"
+	TargetPRReopened = "pr_reopened"
+	TargetPRReady    = "pr_ready"
 )
 
+// state stores.
+const (
+	StoreBolt   = "bolt"
+	StoreMemory = "memory"
+	StoreFile   = "file"
+)
+
+// defaultLimit is the number of new items notified per check when the limit is not configured.
+const defaultLimit = 10
+
 // Watcher represents watcher for github some activities.
 type Watcher struct {
 	configPath  string
@@ -36,63 +53,229 @@ type Watcher struct {
 	worker      *petelgeuse.Manager
 	interval    string
 	accessToken string
+	storeType   string
+	dbPath      string
+	namespace   string
+	store       lmdb.Store
+	githubURL   string
+	uploadURL   string
+	hostTokens  map[string]string
+	app         *AppOption
+	// defined are notifiers defined in config, keyed by name.
+	defined map[string]*definedNotifier
+	// clients are github clients keyed by host.
+	clients map[string]*github.Client
+	// resumeC fires when rate limit which paused checks is reset.
+	resumeC <-chan time.Time
+	// digest is "tick" or window to send digest, or empty to notify immediately.
+	digest   string
+	digestMu sync.Mutex
+	digests  map[Notifier]*digestNotifier
+	// queue keeps failed notifications to retry.
+	queue      *retryQueue
+	maxRetries int
+	retryMu    sync.Mutex
+	retries    map[Notifier]*retryNotifier
+}
+
+// Option specifies optional parameters of watcher.
+type Option struct {
+	// Store is the backend to store watching state (bolt, memory or file). default is bolt.
+	Store string
+	// DBPath is the file path of bolt or file store. default is under ~/.config/watchcat.
+	DBPath string
+	// Namespace isolates watching state from other watchers sharing the same store.
+	Namespace string
+	// GitHubURL is the url of github enterprise server. default is github.com.
+	GitHubURL string
+	// GitHubUploadURL is the upload url of github enterprise server. default is derived from GitHubURL.
+	GitHubUploadURL string
+	// HostTokens are access tokens of github hosts other than GitHubURL, keyed by host name.
+	HostTokens map[string]string
+	// App authenticates as installation of github app instead of access token.
+	App *AppOption
+	// Digest batches notifications into one digest per notifier.
+	// it is "tick" to send after each check, or duration like "1h" to send per window.
+	Digest string
+	// MaxRetries is the number of retries of failed notifications before giving up. default is 8.
+	MaxRetries int
+}
+
+// AppOption specifies github app to authenticate as.
+type AppOption struct {
+	ID             int64
+	InstallationID int64
+	// PrivateKeyPath is the path of PEM file of the app's private key.
+	PrivateKeyPath string
 }
 
 // Config represents cofiguration of watching targets.
 type Config struct {
+	Limit int           `toml:"limit"`
 	Repos []*RepoConfig `toml:"repos"`
+	// Templates are message templates keyed by notifier name and then by target (or "default").
+	Templates map[string]map[string]string `toml:"templates"`
+	// Notifiers are named notifier definitions which repositories route events to.
+	Notifiers map[string]toml.Primitive `toml:"notifiers"`
+	md        toml.MetaData
 }
 
 // RepoConfig represents target repository to watch.
 type RepoConfig struct {
-	Owner     string   `toml:"owner"`
-	Name      string   `toml:"name"`
-	Targets   []string `toml:"targets"`
+	Owner   string   `toml:"owner"`
+	Name    string   `toml:"name"`
+	Targets []string `toml:"targets"`
+	Limit   int      `toml:"limit"`
+	// GitHubURL overrides github host of the repository.
+	GitHubURL string `toml:"github_url"`
+	// Constraint selects versions of releases and tags, like ">=1.20, <2".
+	Constraint string `toml:"constraint"`
+	// Prerelease and Draft include prereleases and drafts.
+	Prerelease bool `toml:"prerelease"`
+	Draft      bool `toml:"draft"`
+	// TagPattern is the regular expression which names of releases and tags must match.
+	TagPattern string `toml:"tag_pattern"`
+	// Bump is the lowest level of version bump to notify (major, minor or patch). default is patch.
+	Bump string `toml:"bump"`
+	// Filters select issues and pull requests to notify, keyed by target.
+	Filters map[string]*IssueFilter `toml:"filters"`
+	// Branches are names or glob patterns of branches whose commits are watched. default is the default branch.
+	Branches []string `toml:"branches"`
+	// Paths limit commits to those changing files under (or matching glob of) them.
+	Paths []string `toml:"paths"`
+	// Notifiers are names of notifiers receiving events of the repository.
+	// notifiers of command line are used if it is empty.
+	Notifiers []string `toml:"notifiers"`
+	// Routes override Notifiers per target.
+	Routes    map[string][]string `toml:"routes"`
 	avatarURL string
 }
 
 // New creates new watchcat instance.
-func New(confingPath string, interval string, accessToken string) *Watcher {
+// opts can be omitted to use default options.
+func New(configPath string, interval string, accessToken string, opts ...Option) *Watcher {
 	worker := petelgeuse.New(&petelgeuse.Option{
 		WorkerSize: 10,
 		QueueSize:  1000,
 	})
 
-	return &Watcher{
-		configPath:  confingPath,
+	w := &Watcher{
+		configPath:  configPath,
 		worker:      worker,
 		notifiers:   notifiers{},
 		interval:    interval,
 		accessToken: accessToken,
+		digests:     map[Notifier]*digestNotifier{},
+		retries:     map[Notifier]*retryNotifier{},
+	}
+	for _, op := range opts {
+		w.apply(op)
+	}
+	return w
+}
+
+// apply sets the fields specified in op, so that later options override earlier ones.
+func (w *Watcher) apply(op Option) {
+	if op.Store != "" {
+		w.storeType = op.Store
+	}
+	if op.DBPath != "" {
+		w.dbPath = op.DBPath
+	}
+	if op.Namespace != "" {
+		w.namespace = op.Namespace
+	}
+	if op.GitHubURL != "" {
+		w.githubURL = op.GitHubURL
+	}
+	if op.GitHubUploadURL != "" {
+		w.uploadURL = op.GitHubUploadURL
+	}
+	if op.HostTokens != nil {
+		w.hostTokens = op.HostTokens
+	}
+	if op.App != nil {
+		w.app = op.App
+	}
+	if op.Digest != "" {
+		w.digest = op.Digest
+	}
+	if op.MaxRetries != 0 {
+		w.maxRetries = op.MaxRetries
 	}
 }
 
 // Watch starts to watch repositories.
 func (w *Watcher) Watch() error {
 	w.worker.Start()
-	if err := lmdb.Connect(); err != nil {
+	store, err := openStore(w.storeType, w.dbPath)
+	if err != nil {
+		return err
+	}
+	w.store = lmdb.WithNamespace(store, w.namespace)
+	w.queue = &retryQueue{
+		store:      w.store,
+		maxRetries: w.maxRetries,
+	}
+	connectOption := &github.ConnectOption{
+		AccessToken: w.accessToken,
+		BaseURL:     w.githubURL,
+		UploadURL:   w.uploadURL,
+		Store:       w.store,
+	}
+	if w.app != nil {
+		keyPath, err := expandHome(w.app.PrivateKeyPath)
+		if err != nil {
+			return err
+		}
+		key, err := ioutil.ReadFile(keyPath)
+		if err != nil {
+			return err
+		}
+		connectOption.AppID = w.app.ID
+		connectOption.InstallationID = w.app.InstallationID
+		connectOption.AppPrivateKey = key
+	}
+	client, err := github.Connect(connectOption)
+	if err != nil {
 		return err
 	}
-	if w.accessToken == "" {
-		github.Connect(nil)
-	} else {
-		github.Connect(&github.ConnectOption{
-			AccessToken: w.accessToken,
-		})
+	w.clients = map[string]*github.Client{
+		github.Host(w.githubURL): client,
 	}
 
 	defer func() {
-		github.Disconnect()
-		lmdb.Disconnect()
+		w.store.Close()
 		w.worker.StopImmediately()
 	}()
 
+	for _, n := range w.notifiers {
+		if f, ok := n.(*FeedNotifier); ok {
+			srv, err := f.serve(w.store)
+			if err != nil {
+				return err
+			}
+			defer srv.Close()
+		}
+	}
+
+	var digestC <-chan time.Time
+	if w.digest != "" && w.digest != DigestTick {
+		window, err := time.ParseDuration(w.digest)
+		if err != nil {
+			return fmt.Errorf("invalid digest: %s", w.digest)
+		}
+		digestTicker := time.NewTicker(window)
+		defer digestTicker.Stop()
+		digestC = digestTicker.C
+	}
+
 	config, err := readConfig(w.configPath)
 	if err != nil {
 		return err
 	}
 
-	w.check(config.Repos)
+	w.check(config)
 
 	interval, err := time.ParseDuration(w.interval)
 	if err != nil {
@@ -106,14 +289,21 @@ func (w *Watcher) Watch() error {
 	for {
 		select {
 		case <-w.ticker.C:
-			config, err := readConfig(w.configPath)
-			if err != nil {
-				continue
-			}
-			w.check(config.Repos)
+		case <-w.resumeC:
+		case <-digestC:
+			w.flushDigests()
+			continue
 		case <-stopC:
+			w.flushDigests()
 			return nil
 		}
+		w.resumeC = nil
+
+		config, err := readConfig(w.configPath)
+		if err != nil {
+			continue
+		}
+		w.check(config)
 	}
 }
 
@@ -122,43 +312,206 @@ func (w *Watcher) AddNotifier(n Notifier) {
 	w.notifiers = append(w.notifiers, n)
 }
 
-func (w *Watcher) check(repos []*RepoConfig) {
-	for _, repo := range repos {
-		avatarURL, err := fetchAvatarURL(repo.Owner)
+func (w *Watcher) check(config *Config) {
+	limit := config.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+
+	ns := make(notifiers, 0, len(w.notifiers))
+	for _, n := range w.notifiers {
+		wrapped, err := w.wrap(n, notifierName(n), config.Templates)
+		if err != nil {
+			w.notifiers.Error(err)
+		}
+		ns = append(ns, wrapped)
+	}
+	defined, err := w.defineNotifiers(config)
+	if err != nil {
+		ns.Error(err)
+	}
+
+	// retry notifications failed in previous checks before new ones.
+	if w.queue != nil {
+		named := map[string]Notifier{}
+		for _, n := range w.notifiers {
+			named[notifierName(n)] = n
+		}
+		for name, d := range w.defined {
+			named[name] = d.notifier
+		}
+		for _, err := range w.queue.retry(named) {
+			ns.Error(err)
+		}
+	}
+
+	// wg tracks checks of this tick to send digest after them.
+	var wg sync.WaitGroup
+	add := func(t petelgeuse.Task) {
+		wg.Add(1)
+		w.worker.Add(&trackedTask{Task: t, wg: &wg})
+	}
+	for _, repo := range config.Repos {
+		if repo.Limit <= 0 {
+			repo.Limit = limit
+		}
+
+		client, err := w.client(repo)
+		if err != nil {
+			ns.Error(err)
+			continue
+		}
+
+		// don't queue checks which would fail until rate limit is reset.
+		if reset, limited := client.RateLimitedUntil(); limited {
+			if w.resumeC == nil {
+				w.resumeC = time.After(time.Until(reset))
+			}
+			continue
+		}
+
+		avatarURL, err := fetchAvatarURL(w.store, client, repo.Owner)
 		if err == nil {
 			repo.avatarURL = avatarURL
 		}
 
+		if err := checkFilters(repo); err != nil {
+			ns.Error(err)
+		}
+
+		// state changes of pull requests are checked together.
+		prStates := map[string]notifiers{}
 		for _, target := range repo.Targets {
+			tns, err := routeNotifiers(repo, target, ns, defined)
+			if err != nil {
+				ns.Error(err)
+			}
+
 			switch target {
 			case TargetRelease:
-				w.worker.Add(&ReleaseChecker{
+				add(&ReleaseChecker{
 					repo:      repo,
-					notifiers: w.notifiers,
+					notifiers: tns,
+					store:     w.store,
+					client:    client,
 				})
 			case TargetCommit:
-				w.worker.Add(&CommitChecker{
+				add(&CommitChecker{
 					repo:      repo,
-					notifiers: w.notifiers,
+					notifiers: tns,
+					store:     w.store,
+					client:    client,
 				})
 			case TargetIssue:
-				w.worker.Add(&IssueChecker{
+				add(&IssueChecker{
 					repo:      repo,
-					notifiers: w.notifiers,
+					notifiers: tns,
+					store:     w.store,
+					client:    client,
 				})
 			case TargetPR:
-				w.worker.Add(&PRChecker{
+				add(&PRChecker{
 					repo:      repo,
-					notifiers: w.notifiers,
+					notifiers: tns,
+					store:     w.store,
+					client:    client,
 				})
 			case TargetTag:
-				w.worker.Add(&TagChecker{
+				add(&TagChecker{
 					repo:      repo,
-					notifiers: w.notifiers,
+					notifiers: tns,
+					store:     w.store,
+					client:    client,
 				})
+			case TargetPRMerged, TargetPRClosed, TargetPRReopened, TargetPRReady:
+				prStates[target] = tns
 			}
 		}
+		if len(prStates) > 0 {
+			add(&PRStateChecker{
+				repo:      repo,
+				notifiers: prStates,
+				store:     w.store,
+				client:    client,
+			})
+		}
+	}
+
+	if w.digest == DigestTick {
+		go func() {
+			wg.Wait()
+			w.flushDigests()
+		}()
+	}
+}
+
+// wrap wraps notifier named name to retry failed notifications, to render templates in config, and to batch them into digest.
+// templates render digests instead of notifications batched into them.
+// notifier without templates is returned with error if templates are invalid.
+func (w *Watcher) wrap(n Notifier, name string, templates map[string]map[string]string) (Notifier, error) {
+	t, err := withTemplate(w.retryOf(n, name), name, templates)
+	return w.digestOf(n, name, t), err
+}
+
+// client returns github client for the host of repo, connecting to it at first.
+func (w *Watcher) client(repo *RepoConfig) (*github.Client, error) {
+	u := repo.GitHubURL
+	if u == "" {
+		u = w.githubURL
+	}
+	host := github.Host(u)
+	if c, ok := w.clients[host]; ok {
+		return c, nil
+	}
+
+	c, err := github.Connect(&github.ConnectOption{
+		AccessToken: w.hostTokens[host],
+		BaseURL:     u,
+		Store:       w.store,
+	})
+	if err != nil {
+		return nil, err
+	}
+	w.clients[host] = c
+	return c, nil
+}
+
+func openStore(storeType string, dbPath string) (lmdb.Store, error) {
+	switch storeType {
+	case "", StoreBolt:
+		p, err := storePath(dbPath, "watchcat.db")
+		if err != nil {
+			return nil, err
+		}
+		return lmdb.OpenBolt(p)
+	case StoreMemory:
+		return lmdb.NewMemoryStore(), nil
+	case StoreFile:
+		p, err := storePath(dbPath, "watchcat.json")
+		if err != nil {
+			return nil, err
+		}
+		return lmdb.OpenFile(p)
 	}
+	return nil, fmt.Errorf("invalid store: %s", storeType)
+}
+
+func storePath(dbPath string, defaultName string) (string, error) {
+	if dbPath == "" {
+		return lmdb.DefaultPath(defaultName)
+	}
+	return expandHome(dbPath)
+}
+
+func expandHome(p string) (string, error) {
+	if !strings.HasPrefix(p, "~/") {
+		return p, nil
+	}
+	hd, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(hd, p[2:]), nil
 }
 
 func readConfig(path string) (*Config, error) {
@@ -185,47 +538,62 @@ func readConfigFromURL(url string) (*Config, error) {
 	}
 
 	var config Config
-	if _, err := toml.DecodeReader(res.Body, &config); err != nil {
+	md, err := toml.DecodeReader(res.Body, &config)
+	if err != nil {
 		return nil, err
 	}
+	config.md = md
 	return &config, nil
 }
 
 func readConfigFromFilePath(fpath string) (*Config, error) {
 	var config Config
-	fp := strings.Replace(fpath, "file://", "", 1)
-	if fp[:2] == "~/" {
-		hd, err := homedir.Dir()
-		if err != nil {
-			return nil, err
-		}
-		fp = filepath.Join(hd, fp[2:])
+	fp, err := expandHome(strings.Replace(fpath, "file://", "", 1))
+	if err != nil {
+		return nil, err
 	}
-	if _, err := toml.DecodeFile(fp, &config); err != nil {
+	md, err := toml.DecodeFile(fp, &config)
+	if err != nil {
 		return nil, err
 	}
+	config.md = md
 	return &config, nil
 }
 
-func fetchAvatarURL(ownerName string) (string, error) {
+func fetchAvatarURL(store lmdb.Store, client *github.Client, ownerName string) (string, error) {
+	// the same owner name may exist on other hosts.
+	cacheName := ownerName
+	if host := storeHost(client); host != "" {
+		cacheName = host + "/" + ownerName
+	}
+
 	cache := &lmdb.Owner{
-		Name: ownerName,
+		Name: cacheName,
 	}
 	// expiration time of cache is one day.
-	if err := cache.Read(); err == nil && time.Now().Before(cache.CachedAt.Add(24*time.Hour)) {
+	if err := cache.Read(store); err == nil && time.Now().Before(cache.CachedAt.Add(24*time.Hour)) {
 		return cache.AvatarURL, nil
 	}
 
-	owner, err := github.GetOwner(context.Background(), ownerName)
+	owner, err := client.GetOwner(context.Background(), ownerName)
 	if err != nil {
 		return "", err
 	}
 	cache = &lmdb.Owner{
-		Name:      ownerName,
+		Name:      cacheName,
 		AvatarURL: owner.GetAvatarURL(),
 		CachedAt:  time.Now(),
 	}
-	cache.Write()
+	cache.Write(store)
 
 	return owner.GetAvatarURL(), nil
 }
+
+// storeHost returns host of client to separate its state in the store.
+// it is empty for github.com, to keep state stored before other hosts were supported.
+func storeHost(client *github.Client) string {
+	if host := client.Host(); host != "github.com" {
+		return host
+	}
+	return ""
+}
@@ -0,0 +1,19 @@
+package watchcat
+
+import "testing"
+
+func TestNewOptions(t *testing.T) {
+	w := New("config.toml", "10m", "token")
+	if w.configPath != "config.toml" || w.storeType != "" || w.maxRetries != 0 {
+		t.Errorf("unexpected watcher without options: %+v", w)
+	}
+
+	// later options override fields specified in them.
+	w = New("config.toml", "10m", "token",
+		Option{Store: "file", DBPath: "a.json", MaxRetries: 3},
+		Option{DBPath: "b.json", Namespace: "team-a"},
+	)
+	if w.storeType != "file" || w.dbPath != "b.json" || w.namespace != "team-a" || w.maxRetries != 3 {
+		t.Errorf("unexpected watcher with options: %+v", w)
+	}
+}